@@ -0,0 +1,100 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// openActive listens for a new active data connection
+// and announces its address to the server.
+func (c *Client) openActive(ctx context.Context) (*net.TCPListener, error) {
+	ip, err := addrIP(c.conn.LocalAddr())
+	if err != nil {
+		return nil, err
+	}
+	var lc net.ListenConfig
+	ln, err := lc.Listen(ctx, "tcp", net.JoinHostPort(ip.String(), "0"))
+	if err != nil {
+		return nil, err
+	}
+	tl := ln.(*net.TCPListener)
+	if err := c.sendPort(ctx, tl.Addr().(*net.TCPAddr)); err != nil {
+		tl.Close()
+		return nil, err
+	}
+	return tl, nil
+}
+
+// sendPort announces addr to the server, using EPRT for IPv6 addresses
+// and PORT otherwise.
+func (c *Client) sendPort(ctx context.Context, addr *net.TCPAddr) error {
+	if addr.IP.To4() == nil {
+		c.lastDataAddr = &DataAddr{TCPAddr: *addr, Command: "EPRT"}
+		return c.simpleCommandOnce(ctx, "EPRT "+formatEprtArg(addr))
+	}
+	arg, err := formatPortArg(addr)
+	if err != nil {
+		return err
+	}
+	c.lastDataAddr = &DataAddr{TCPAddr: *addr, Command: "PORT"}
+	return c.simpleCommandOnce(ctx, "PORT "+arg)
+}
+
+// formatPortArg formats addr as argument to the PORT command:
+//
+//	h1,h2,h3,h4,p1,p2
+func formatPortArg(addr *net.TCPAddr) (string, error) {
+	ip := addr.IP.To4()
+	if ip == nil {
+		return "", errors.New("ftp: PORT requires an IPv4 address")
+	}
+	return fmt.Sprintf("%d,%d,%d,%d,%d,%d", ip[0], ip[1], ip[2], ip[3], addr.Port>>8, addr.Port&0xff), nil
+}
+
+// formatEprtArg formats addr as argument to the EPRT command
+// as defined in RFC 2428:
+//
+//	|proto|addr|port|
+func formatEprtArg(addr *net.TCPAddr) string {
+	proto := 2
+	if addr.IP.To4() != nil {
+		proto = 1
+	}
+	return fmt.Sprintf("|%d|%s|%d|", proto, addr.IP, addr.Port)
+}
+
+// acceptActive waits for the server to connect to ln.
+// It gives up when ctx is done.
+func (c *Client) acceptActive(ctx context.Context, ln *net.TCPListener) (net.Conn, error) {
+	if ctx.Done() != nil {
+		stop := make(chan struct{})
+		defer close(stop)
+		go func() {
+			select {
+			case <-ctx.Done():
+				ln.Close()
+			case <-stop:
+			}
+		}()
+	}
+	if c.DataConnectTimeout > 0 {
+		ln.SetDeadline(time.Now().Add(c.DataConnectTimeout))
+	}
+	conn, err := ln.Accept()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	if err := c.TCPOptions.apply(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c.protectData(conn), nil
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestFormatPortArg(t *testing.T) {
+	const expected = "192,0,2,47,4,7"
+	arg, err := formatPortArg(&net.TCPAddr{IP: net.IPv4(192, 0, 2, 47), Port: 1031})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if arg != expected {
+		t.Errorf("arg = %q (expected %q)", arg, expected)
+	}
+}
+
+func TestFormatEprtArg(t *testing.T) {
+	tests := []struct {
+		Addr *net.TCPAddr
+		Arg  string
+	}{
+		{&net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 1031}, "|2|2001:db8::1|1031|"},
+		{&net.TCPAddr{IP: net.IPv4(192, 0, 2, 47), Port: 1031}, "|1|192.0.2.47|1031|"},
+	}
+	for i, tt := range tests {
+		if arg := formatEprtArg(tt.Addr); arg != tt.Arg {
+			t.Errorf("tests[%d]: expected %q (got %q)", i, tt.Arg, arg)
+		}
+	}
+}
+
+func TestActiveMode(t *testing.T) {
+	const expected = "Hello, World\n"
+
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("hello.txt", []byte(expected))
+	c := s.dial(ctx)
+	c.ActiveMode = true
+
+	var buf bytes.Buffer
+	if _, err := c.RetrieveFile(ctx, "hello.txt", &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != expected {
+		t.Errorf("data = %q (expected %q)", buf.String(), expected)
+	}
+}
+
+func TestActiveModeAcceptTimeout(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.handle("RETR", func(sc *serverConn, arg string) {
+		sc.reply(150, "Never connecting")
+		sc.reply(425, "Can't open data connection")
+	})
+	c := s.dial(ctx)
+	c.ActiveMode = true
+
+	tctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.RetrieveFile(tctx, "hello.txt", new(bytes.Buffer)); err != context.DeadlineExceeded {
+		t.Errorf("err = %v (expected %v)", err, context.DeadlineExceeded)
+	}
+	checkUsable(ctx, t, c)
+}
+
+// checkUsable checks that each command sent over the control connection
+// of c gets its own reply.
+func checkUsable(ctx context.Context, t *testing.T, c *Client) {
+	t.Helper()
+	if err := c.NoOp(ctx); err != nil {
+		t.Fatalf("NoOp: %v", err)
+	}
+	if reply, err := c.Do(ctx, "SYST"); err != nil || reply.Code != CodeSystemType {
+		t.Errorf("SYST reply = %v, %v (expected %v)", reply, err, CodeSystemType)
+	}
+}
+
+func TestActiveModeDataConnectTimeout(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.handle("RETR", func(sc *serverConn, arg string) {
+		sc.reply(150, "Never connecting")
+		sc.reply(425, "Can't open data connection")
+	})
+	c := s.dial(ctx)
+	c.ActiveMode = true
+	c.DataConnectTimeout = 50 * time.Millisecond
+
+	start := time.Now()
+	if _, err := c.RetrieveFile(ctx, "hello.txt", new(bytes.Buffer)); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("err = %v (expected %v)", err, os.ErrDeadlineExceeded)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("RetrieveFile returned after %v", d)
+	}
+	checkUsable(ctx, t, c)
+}
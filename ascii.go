@@ -0,0 +1,83 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"bufio"
+	"io"
+)
+
+// asciiConn translates line endings on a data connection in ASCII mode,
+// as required by RFC 959: a LF written to it is sent as CRLF, and a CRLF
+// read from it is returned as LF. A CRLF written to it is sent unchanged
+// and a lone CR is passed through in both directions.
+type asciiConn struct {
+	rwc io.ReadWriteCloser
+	br  *bufio.Reader
+	cr  bool  // last byte written was CR
+	err error // read error to return by the next Read
+}
+
+func newASCIIConn(rwc io.ReadWriteCloser) *asciiConn {
+	return &asciiConn{rwc: rwc, br: bufio.NewReader(rwc)}
+}
+
+// Read reads from the connection, replacing CRLF by LF. It doesn't block
+// once data is available, except to look ahead after a CR. An error
+// encountered after reading some data is returned by the next Read.
+func (a *asciiConn) Read(p []byte) (n int, err error) {
+	if a.err != nil {
+		err, a.err = a.err, nil
+		return 0, err
+	}
+	for n < len(p) {
+		b, err := a.br.ReadByte()
+		if err != nil {
+			if n > 0 {
+				a.err = err
+				return n, nil
+			}
+			return 0, err
+		}
+		if b == '\r' {
+			next, err := a.br.Peek(1)
+			if err == nil && next[0] == '\n' {
+				continue
+			} else if err != nil {
+				a.err = err
+				p[n] = b
+				return n + 1, nil
+			}
+		}
+		p[n] = b
+		n++
+		if a.br.Buffered() == 0 {
+			break
+		}
+	}
+	return n, nil
+}
+
+// Write writes p to the connection, replacing each LF that isn't preceded
+// by CR by CRLF. It returns the number of bytes of p written.
+func (a *asciiConn) Write(p []byte) (n int, err error) {
+	buf := make([]byte, 0, len(p)+len(p)/8)
+	cr := a.cr
+	for _, b := range p {
+		if b == '\n' && !cr {
+			buf = append(buf, '\r')
+		}
+		buf = append(buf, b)
+		cr = b == '\r'
+	}
+	if _, err := a.rwc.Write(buf); err != nil {
+		return 0, err
+	}
+	a.cr = cr
+	return len(p), nil
+}
+
+// Close closes the underlying data connection.
+func (a *asciiConn) Close() error {
+	return a.rwc.Close()
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+var asciiTests = []struct {
+	Local, Network string
+}{
+	{"", ""},
+	{"one\ntwo\n", "one\r\ntwo\r\n"},
+	{"one\r\ntwo\n", "one\r\ntwo\r\n"},
+	{"no newline", "no newline"},
+	{"lone\rcr\n\n", "lone\rcr\r\n\r\n"},
+}
+
+func TestASCIIWrite(t *testing.T) {
+	for i, tt := range asciiTests {
+		var buf bytes.Buffer
+		a := newASCIIConn(MockRWC{R: new(bytes.Buffer), W: &buf})
+		// Write a byte at a time to split CRLF across writes.
+		for j := 0; j < len(tt.Local); j++ {
+			if _, err := a.Write([]byte{tt.Local[j]}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if buf.String() != tt.Network {
+			t.Errorf("tests[%d]: expected %q (got %q)", i, tt.Network, buf.String())
+		}
+	}
+}
+
+func TestASCIIRead(t *testing.T) {
+	for i, tt := range asciiTests {
+		if tt.Local == "one\r\ntwo\n" {
+			continue // CRLF in local input isn't preserved
+		}
+		a := newASCIIConn(MockRWC{R: bytes.NewBufferString(tt.Network), W: new(bytes.Buffer)})
+		b, err := io.ReadAll(iotest.OneByteReader(a))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tt.Local {
+			t.Errorf("tests[%d]: expected %q (got %q)", i, tt.Local, b)
+		}
+	}
+}
+
+// errReader returns Data by the first Read, Err by the second and io.EOF
+// afterwards.
+type errReader struct {
+	Data  string
+	Err   error
+	reads int
+}
+
+func (r *errReader) Read(p []byte) (int, error) {
+	r.reads++
+	switch r.reads {
+	case 1:
+		return copy(p, r.Data), nil
+	case 2:
+		return 0, r.Err
+	}
+	return 0, io.EOF
+}
+
+func TestASCIIReadError(t *testing.T) {
+	for i, data := range []string{"one\n", "one\r"} {
+		a := newASCIIConn(struct {
+			io.Reader
+			io.WriteCloser
+		}{&errReader{Data: data, Err: iotest.ErrTimeout}, MockRWC{W: new(bytes.Buffer)}})
+		b, err := io.ReadAll(a)
+		if string(b) != data || err != iotest.ErrTimeout {
+			t.Errorf("tests[%d]: expected %q, %v (got %q, %v)", i, data, iotest.ErrTimeout, b, err)
+		}
+	}
+}
+
+func TestTextTranslation(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("a.txt", []byte("one\r\ntwo\r\n"))
+	c := s.dial(ctx)
+
+	_, conn, err := c.Text(ctx, "RETR a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(conn)
+	if cerr := conn.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "one\ntwo\n" {
+		t.Errorf("retrieved %q", b)
+	}
+
+	_, conn, err = c.Text(ctx, "STOR b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(conn, "one\ntwo\r\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := s.file("b.txt"); string(b) != "one\r\ntwo\r\n" {
+		t.Errorf("stored %q", b)
+	}
+}
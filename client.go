@@ -5,37 +5,347 @@
 package ftp
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"crypto/tls"
 	"errors"
+	"fmt"
+	"io"
 	"net"
 	"net/textproto"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
 )
 
 // A Client is an FTP client.
-// A single FTP connection cannot handle simultaneous transfers.
+// A single FTP connection cannot handle simultaneous transfers: starting
+// a transfer while another one is in progress returns ErrTransferInProgress.
 type Client struct {
 	conn    net.Conn
 	proto   *textproto.Conn
 	Welcome Reply
+
+	// ActiveMode makes the server connect to the client for data transfers
+	// (PORT) instead of the client connecting to the server (PASV).
+	ActiveMode bool
+
+	// IgnorePASVAddress makes passive data connections dial the IP address
+	// of the control connection instead of the one advertised by PASV,
+	// keeping only the advertised port. This works around servers behind
+	// NAT that advertise their private address, but breaks multi-homed
+	// servers that hand out a different address on purpose.
+	IgnorePASVAddress bool
+
+	// ForcePASV makes passive data connections use PASV instead of trying
+	// EPSV first, for servers that mishandle EPSV. It is ignored for IPv6
+	// servers, which require EPSV. ForceEPSV makes them use EPSV only,
+	// never falling back to PASV, and takes precedence over ForcePASV.
+	ForcePASV bool
+	ForceEPSV bool
+
+	// PassiveAddrFunc, if set, returns the address to dial for passive data
+	// connections instead of PassiveAddr, for example to apply a NAT
+	// workaround that IgnorePASVAddress doesn't cover. It may call
+	// PassiveAddr and adjust its result.
+	PassiveAddrFunc func(ctx context.Context, c *Client) (*net.TCPAddr, error)
+
+	// DataConnectTimeout limits the time to establish a data connection,
+	// independent of the deadline of the context, so a data connection
+	// blocked by NAT or a firewall fails fast. For active data connections
+	// it limits the time waiting for the server to connect; the transfer
+	// is then aborted, so the control connection remains usable. Zero
+	// means no limit.
+	DataConnectTimeout time.Duration
+
+	// UsePRET makes passive data connections announce the transfer command
+	// using PRET before EPSV or PASV, as required by distributed servers
+	// like DrFTPD to pick the node serving the transfer. PRET is also sent
+	// if the server advertises it.
+	UsePRET bool
+
+	// DialFunc, if set, dials passive data connections, for example to bind
+	// a source address or to route them through a proxy. If nil, the zero
+	// net.Dialer is used.
+	DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	// Encoding converts pathnames and other text on the control connection
+	// and in listings for servers that don't support UTF-8.
+	// If nil, text is passed through unchanged.
+	Encoding Encoding
+
+	// UploadRateLimit and DownloadRateLimit limit the throughput of data
+	// transfers to the number of bytes per second. Zero means no limit.
+	UploadRateLimit   int64
+	DownloadRateLimit int64
+
+	// MaxReplySize limits the size in bytes of a multiline reply, so a
+	// broken or hostile server can't exhaust memory. Zero means a limit of
+	// DefaultMaxReplySize. The connection is unusable once it is exceeded.
+	MaxReplySize int
+
+	// MaxLineLength limits the length in bytes of a reply line, excluding
+	// its line ending. Zero means a limit of DefaultMaxLineLength. The
+	// connection is unusable once it is exceeded.
+	MaxLineLength int
+
+	// EnablePipelining allows Pipeline to send multiple commands before
+	// reading their replies. Many servers don't support this.
+	EnablePipelining bool
+
+	// FollowSymlinks makes Walk and RetrieveDir follow symbolic links
+	// instead of passing them on without descending into them.
+	FollowSymlinks bool
+
+	// Logger, if set, is called for every command sent and every reply
+	// line received on the control connection, for debugging. The password
+	// sent by Login is masked.
+	Logger func(dir Direction, line string)
+
+	// DisableTLSSessionReuse disables resuming the TLS session of the
+	// control connection on protected data connections.
+	DisableTLSSessionReuse bool
+
+	// VerifySize makes RetrieveFile and RetrieveFileFrom request the size
+	// of the file using SIZE before the transfer and compare it to the number of bytes
+	// received, returning an error wrapping ErrSizeMismatch if they differ.
+	// This catches truncated transfers the server reports as successful.
+	// The check is skipped if the server doesn't support SIZE.
+	VerifySize bool
+
+	// AllocateOnStore makes StoreFile announce the number of bytes to store
+	// using Allocate if the reader implements io.Seeker, for servers that
+	// need ALLO to reserve space. Negative replies to ALLO are ignored.
+	AllocateOnStore bool
+
+	// TCPOptions, if set, configures the TCP sockets of the data
+	// connections. DialWithOptions sets it to DialOptions.TCPOptions.
+	TCPOptions *TCPOptions
+
+	// Retry, if set, decides whether commands and the opening of data
+	// transfers that fail are retried and after which delay. See RetryPolicy.
+	Retry RetryPolicy
+
+	tlsConfig     *tls.Config
+	dataProtected bool // PROT P is active
+	epsvFailed    bool // EPSV was rejected, use PASV
+	features      Features
+	system        string
+	compressLevel int    // zlib level if MODE Z is active
+	dataType      string // last type set by TYPE
+	bufferSize    int    // see SetBufferSize
+	lastDataAddr  *DataAddr
+	listCommand   string // command used by the last ListEntries
+
+	// Session state restored by Reconnect.
+	network, addr      string
+	dialOpts           DialOptions
+	username, password string
+	authConfig         *tls.Config // config passed to AuthTLS
+	dir                string      // working directory, if changed
+
+	// serverHost and serverIP are the host dialed through a proxy and its
+	// IP address, because the remote address of conn is the proxy's.
+	// serverIP is nil until known if the host couldn't be resolved.
+	serverHost string
+	serverIP   net.IP
+
+	// mu serializes the exchanges on the control connection
+	// and guards the fields below.
+	mu           sync.Mutex
+	transferring *transferConn // active transfer, if any
+	opening      bool          // a transfer is being opened
+	lastUsed     time.Time     // last time a reply was read
 }
 
+// DefaultMaxReplySize is the default limit of Client.MaxReplySize.
+const DefaultMaxReplySize = 4 << 20
+
+// DefaultWelcomeTimeout is the default of DialOptions.WelcomeTimeout.
+var DefaultWelcomeTimeout = 30 * time.Second
+
+// DefaultMaxLineLength is the default limit of Client.MaxLineLength.
+const DefaultMaxLineLength = 64 << 10
+
 // Dial connects to an FTP server using the provided context.
 func Dial(ctx context.Context, network, addr string) (*Client, error) {
+	return DialWithOptions(ctx, network, addr, DialOptions{})
+}
+
+// DialOptions configures how DialWithOptions connects to a server.
+type DialOptions struct {
+	// Dialer dials the control connection, for example to bind a source
+	// address or to set a timeout. If nil, the zero net.Dialer is used.
+	Dialer *net.Dialer
+
+	// TLSConfig enables implicit FTPS like DialTLS.
+	TLSConfig *tls.Config
+
+	// WelcomeTimeout limits the time to read the welcome message,
+	// independent of the deadline of the context, so a server that accepts
+	// connections without ever greeting, like a load balancer without
+	// backends, doesn't block Dial forever. Zero means a limit of
+	// DefaultWelcomeTimeout and a negative value means no limit.
+	WelcomeTimeout time.Duration
+
+	// ProxyDialer, if set, dials the control connection and the passive
+	// data connections through a proxy, like a SOCKS5 dialer from
+	// golang.org/x/net/proxy. It takes precedence over Dialer. Because EPSV
+	// data connections are made to the IP address of the server, the host
+	// of addr is resolved locally, preferring the address family of
+	// network. If that fails, for example because DNS only works through
+	// the proxy, PASV is used until its reply reveals the address of the
+	// server. Active mode isn't supported.
+	ProxyDialer ContextDialer
+
+	// TCPOptions, if set, configures the TCP socket of the control
+	// connection and is copied to Client.TCPOptions for the data
+	// connections.
+	TCPOptions *TCPOptions
+}
+
+// TCPOptions configures the TCP socket of a connection.
+type TCPOptions struct {
+	// KeepAlive is the interval between TCP keep-alive probes, so a
+	// connection over a dead link fails instead of hanging, for example
+	// during a long transfer that keeps the control connection idle. Like
+	// net.Dialer.KeepAlive, zero leaves the default of the dialer unchanged
+	// and a negative value disables keep-alive probes.
+	KeepAlive time.Duration
+
+	// DisableNoDelay enables Nagle's algorithm, which delays sending small
+	// segments to combine them. By default Go disables it.
+	DisableNoDelay bool
+}
+
+// apply applies o to conn. Connections that aren't TCP connections, like
+// connections dialed through a proxy, are left unchanged.
+func (o *TCPOptions) apply(conn net.Conn) error {
+	tc, ok := conn.(*net.TCPConn)
+	if o == nil || !ok {
+		return nil
+	}
+	switch {
+	case o.KeepAlive < 0:
+		if err := tc.SetKeepAlive(false); err != nil {
+			return err
+		}
+	case o.KeepAlive > 0:
+		if err := tc.SetKeepAlive(true); err != nil {
+			return err
+		}
+		if err := tc.SetKeepAlivePeriod(o.KeepAlive); err != nil {
+			return err
+		}
+	}
+	return tc.SetNoDelay(!o.DisableNoDelay)
+}
+
+// ContextDialer dials connections. It is implemented by net.Dialer and the
+// dialers of golang.org/x/net/proxy.
+type ContextDialer interface {
+	DialContext(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// DialWithOptions connects to an FTP server like Dial, configured by opts.
+func DialWithOptions(ctx context.Context, network, addr string, opts DialOptions) (*Client, error) {
 	if !strings.HasPrefix(network, "tcp") {
 		return nil, errors.New("ftp: only TCP connections are supported")
 	}
-	var d net.Dialer
-	c, err := d.DialContext(ctx, network, addr)
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	var config *tls.Config
+	if opts.TLSConfig != nil {
+		config = sessionConfig(opts.TLSConfig, host)
+	}
+	var d ContextDialer = new(net.Dialer)
+	if opts.ProxyDialer != nil {
+		d = opts.ProxyDialer
+	} else if opts.Dialer != nil {
+		d = opts.Dialer
+	}
+	var serverIP net.IP
+	if opts.ProxyDialer != nil {
+		serverIP = resolveServerIP(ctx, network, host)
+	}
+	conn, err := d.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.TCPOptions.apply(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if config != nil {
+		tconn := tls.Client(conn, config)
+		if err := tconn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tconn
+	}
+	welcomeTimeout := opts.WelcomeTimeout
+	if welcomeTimeout == 0 {
+		welcomeTimeout = DefaultWelcomeTimeout
+	}
+	if welcomeTimeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(welcomeTimeout))
+	}
+	c, err := NewClient(ctx, conn)
 	if err != nil {
+		conn.Close()
 		return nil, err
 	}
-	return NewClient(ctx, c)
+	if welcomeTimeout > 0 {
+		conn.SetReadDeadline(time.Time{})
+	}
+	c.tlsConfig = config
+	c.TCPOptions = opts.TCPOptions
+	c.network, c.addr, c.dialOpts = network, addr, opts
+	if opts.ProxyDialer != nil {
+		c.DialFunc = opts.ProxyDialer.DialContext
+		c.serverHost, c.serverIP = host, serverIP
+	}
+	return c, nil
+}
+
+// lookupIP looks up the IP addresses of a host. It is replaced by tests.
+var lookupIP = net.DefaultResolver.LookupIP
+
+// resolveServerIP returns the IP address of host to use for data
+// connections on network, preferring IPv4 for "tcp". It returns nil if
+// host can't be resolved.
+func resolveServerIP(ctx context.Context, network, host string) net.IP {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip
+	}
+	family := "ip"
+	switch network {
+	case "tcp4":
+		family = "ip4"
+	case "tcp6":
+		family = "ip6"
+	}
+	ips, err := lookupIP(ctx, family, host)
+	if err != nil || len(ips) == 0 {
+		return nil
+	}
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			return ip
+		}
+	}
+	return ips[0]
 }
 
 // NewClient creates an FTP client from an existing connection.
-// It reads the initial (welcome) message from the server.
+// It reads the initial (welcome) message from the server. Unlike Dial, it
+// doesn't limit the time to read it; set a read deadline on conn for that.
 func NewClient(ctx context.Context, conn net.Conn) (*Client, error) {
 	var err error
 	c := &Client{
@@ -85,8 +395,35 @@ func (c *Client) Close() error {
 	return c.proto.Close()
 }
 
-// Login sends credentials to the server.
+// quitTimeout limits the time CloseGracefully waits for the reply to QUIT.
+const quitTimeout = 5 * time.Second
+
+// CloseGracefully sends QUIT and closes the connection. Unlike Quit, which
+// waits for the reply as long as ctx allows, it waits at most quitTimeout,
+// and unlike Close, it tells the server the session ends. The connection
+// is closed even if QUIT fails or times out, so tearing down a connection
+// to an unresponsive server doesn't leak it. An error is only returned if
+// the server rejects QUIT or closing the connection fails.
+func (c *Client) CloseGracefully(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, quitTimeout)
+	defer cancel()
+	reply, err := c.sendCommand(ctx, "QUIT")
+	cerr := c.Close()
+	if err == nil && !reply.PositiveComplete() {
+		return reply
+	}
+	return cerr
+}
+
+// Login sends credentials to the server. PASS is only sent if the server
+// asks for a password, so servers that accept USER alone are supported.
+// If the welcome reply indicates the client is logged in already,
+// nothing is sent.
 func (c *Client) Login(ctx context.Context, username, password string) error {
+	if c.Welcome.Code == CodeLoggedIn {
+		c.username, c.password = username, password
+		return nil
+	}
 	reply, err := c.sendCommand(ctx, "USER "+username)
 	if err != nil {
 		return err
@@ -100,6 +437,55 @@ func (c *Client) Login(ctx context.Context, username, password string) error {
 	if !reply.PositiveComplete() {
 		return reply
 	}
+	c.username, c.password = username, password
+	return nil
+}
+
+// Reinitialize resets the session to the state right after connecting
+// using REIN, so another user can log in without reconnecting. The
+// parameters of the session, like the transfer type, working directory,
+// MODE Z and data protection, are reset to their defaults.
+func (c *Client) Reinitialize(ctx context.Context) error {
+	reply, err := c.sendCommand(ctx, "REIN")
+	if err != nil {
+		return err
+	}
+	if reply.Code == CodeServiceReadySoon {
+		c.mu.Lock()
+		reply, err = c.readResponse()
+		c.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	if !reply.PositiveComplete() {
+		return reply
+	}
+	c.username, c.password = "", ""
+	c.dataType, c.dir = "", ""
+	c.dataProtected, c.compressLevel = false, 0
+	return nil
+}
+
+// AnonymousLogin logs in as the anonymous user, using the conventional
+// e-mail address "anonymous@" as password.
+func (c *Client) AnonymousLogin(ctx context.Context) error {
+	return c.Login(ctx, "anonymous", "anonymous@")
+}
+
+// SetClientName identifies the client to the server using CLNT, which
+// should be sent right after Login. Some servers log the name or only
+// enable features like resuming transfers for known clients. If the server
+// rejects CLNT or doesn't implement it, an error wrapping ErrUnsupported is
+// returned, which callers that only identify themselves where possible can
+// ignore.
+func (c *Client) SetClientName(ctx context.Context, name string) error {
+	reply, err := c.sendCommand(ctx, "CLNT "+name)
+	if err != nil {
+		return err
+	} else if !reply.PositiveComplete() {
+		return unsupported("CLNT")
+	}
 	return nil
 }
 
@@ -109,6 +495,39 @@ func (c *Client) Do(ctx context.Context, command string) (Reply, error) {
 	return c.sendCommand(ctx, command)
 }
 
+// DoCmd is like Do, but builds the command from verb and args separated by
+// spaces. The verb must consist of ASCII letters and digits only, like
+// XSHA256, and the arguments must not contain control characters, so
+// untrusted arguments can't inject additional commands. Otherwise an error
+// wrapping ErrInvalidCommand is returned without sending anything.
+func (c *Client) DoCmd(ctx context.Context, verb string, args ...string) (Reply, error) {
+	if verb == "" || strings.IndexFunc(verb, func(r rune) bool {
+		return (r < 'A' || r > 'Z') && (r < 'a' || r > 'z') && (r < '0' || r > '9')
+	}) >= 0 {
+		return Reply{}, fmt.Errorf("%w: verb %q", ErrInvalidCommand, verb)
+	}
+	for i, arg := range args {
+		if strings.IndexFunc(arg, unicode.IsControl) >= 0 {
+			return Reply{}, fmt.Errorf("%w: argument %d %q", ErrInvalidCommand, i, arg)
+		}
+	}
+	return c.sendCommand(ctx, strings.Join(append([]string{verb}, args...), " "))
+}
+
+// Site sends a server-specific command using SITE and returns the reply.
+// Many servers implement commands like CHMOD, UTIME or SYMLINK this way.
+func (c *Client) Site(ctx context.Context, args string) (Reply, error) {
+	return c.sendCommand(ctx, "SITE "+args)
+}
+
+// Opts sets an option of a server command using OPTS as defined in
+// RFC 2389 and returns the reply, for example "RETR RESUME" or options
+// that this package doesn't know about. Like with every command, args
+// containing CR, LF or NUL are rejected with ErrInvalidCommand.
+func (c *Client) Opts(ctx context.Context, args string) (Reply, error) {
+	return c.sendCommand(ctx, "OPTS "+args)
+}
+
 func (c *Client) sendCommand(ctx context.Context, command string) (Reply, error) {
 	if ctx.Done() == nil {
 		return c.sendCmd(command)
@@ -126,22 +545,149 @@ func (c *Client) sendCommand(ctx context.Context, command string) (Reply, error)
 	}
 }
 
+// simpleCommand sends a command and returns the reply as error
+// unless it is a positive completion reply. The command is retried
+// according to Retry.
+func (c *Client) simpleCommand(ctx context.Context, command string) error {
+	return c.retry(ctx, func() error {
+		return c.simpleCommandOnce(ctx, command)
+	})
+}
+
+// simpleCommandOnce is like simpleCommand without retrying, for commands
+// sent as part of an operation that is retried as a whole, like transfers.
+func (c *Client) simpleCommandOnce(ctx context.Context, command string) error {
+	reply, err := c.sendCommand(ctx, command)
+	if err != nil {
+		return err
+	} else if !reply.PositiveComplete() {
+		return reply
+	}
+	return nil
+}
+
 type response struct {
 	reply Reply
 	err   error
 }
 
+// ReadReply reads a reply from the control connection without sending
+// a command. It is only needed to drain replies nobody asked for, like the
+// idle warnings some servers send before closing the connection, or to
+// recover a connection that got out of sync. It blocks until a reply
+// arrives or ctx is done, so calling it when no reply is pending hangs,
+// and a reply it takes away won't be read by the command expecting it.
+// If ctx is done first, the next reply is still consumed and discarded.
+func (c *Client) ReadReply(ctx context.Context) (Reply, error) {
+	read := func() (Reply, error) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return c.readResponse()
+	}
+	if ctx.Done() == nil {
+		return read()
+	}
+	resp := make(chan response, 1)
+	go func() {
+		r, err := read()
+		resp <- response{r, err}
+	}()
+	select {
+	case r := <-resp:
+		return r.reply, r.err
+	case <-ctx.Done():
+		return Reply{}, ctx.Err()
+	}
+}
+
 func (c *Client) sendCmd(command string) (Reply, error) {
-	err := c.proto.PrintfLine("%s", command)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	err := c.writeLine(command)
 	if err != nil {
 		return Reply{}, err
 	}
 	return c.readResponse()
 }
 
+// ErrInvalidCommand is returned for commands, including their arguments like
+// paths, that contain CR, LF or NUL, which could inject additional commands.
+var ErrInvalidCommand = errors.New("ftp: command contains CR, LF or NUL")
+
+// checkLine returns ErrInvalidCommand if line contains CR, LF or NUL.
+func checkLine(line string) error {
+	if strings.ContainsAny(line, "\r\n\x00") {
+		return ErrInvalidCommand
+	}
+	return nil
+}
+
+// writeLine writes a line to the control connection.
+func (c *Client) writeLine(line string) error {
+	return c.writeLinePrefix("", line)
+}
+
+// writeLinePrefix writes a line to the control connection preceded by the
+// raw bytes of prefix, like Telnet control codes, which aren't encoded.
+func (c *Client) writeLinePrefix(prefix, line string) error {
+	if err := checkLine(line); err != nil {
+		return err
+	}
+	c.log(Sent, line)
+	line, err := c.encode(line)
+	if err != nil {
+		return err
+	}
+	return c.proto.PrintfLine("%s%s", prefix, line)
+}
+
+// readLine reads a line from the control connection.
+func (c *Client) readLine() (string, error) {
+	line, err := c.readRawLine()
+	if err != nil {
+		return "", err
+	}
+	c.lastUsed = time.Now()
+	line, err = c.decode(line)
+	if err != nil {
+		return "", err
+	}
+	c.log(Received, line)
+	return line, nil
+}
+
+// readRawLine reads a line without its line ending, like the ReadLine
+// method of textproto.Reader, but fails once the line exceeds
+// MaxLineLength instead of reading it into memory as a whole.
+func (c *Client) readRawLine() (string, error) {
+	maxLen := c.MaxLineLength
+	if maxLen <= 0 {
+		maxLen = DefaultMaxLineLength
+	}
+	var line []byte
+	for {
+		frag, err := c.proto.R.ReadSlice('\n')
+		line = append(line, frag...)
+		if len(bytes.TrimRight(line, "\r\n")) > maxLen {
+			return "", fmt.Errorf("ftp: reply line exceeds %d bytes", maxLen)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		} else if err == io.EOF && len(line) > 0 {
+			break // last line without line ending
+		} else if err != nil {
+			return "", err
+		}
+		break
+	}
+	line = bytes.TrimSuffix(line, []byte("\n"))
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	return string(line), nil
+}
+
 // readResponse reads a reply from the server.
 func (c *Client) readResponse() (Reply, error) {
-	line, err := c.proto.ReadLine()
+	line, err := c.readLine()
 	if err != nil {
 		return Reply{}, err
 	} else if len(line) < 4 {
@@ -156,13 +702,22 @@ func (c *Client) readResponse() (Reply, error) {
 	reply := Reply{Code: Code(code)}
 	switch line[3] {
 	case '-':
+		maxSize := c.MaxReplySize
+		if maxSize <= 0 {
+			maxSize = DefaultMaxReplySize
+		}
 		lines := []string{line[4:]}
+		size := len(line)
 		endPrefix := strconv.Itoa(code) + " "
 		for {
-			line, err = c.proto.ReadLine()
+			line, err = c.readLine()
 			if err != nil {
 				break
 			}
+			if size += len(line); size > maxSize {
+				err = fmt.Errorf("ftp: multiline %d reply exceeds %d bytes", code, maxSize)
+				break
+			}
 			if strings.HasPrefix(line, endPrefix) {
 				lines = append(lines, line[len(endPrefix):])
 				break
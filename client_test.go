@@ -6,9 +6,15 @@ package ftp
 import (
 	"bytes"
 	"context"
+	"errors"
+	"io"
+	"net"
 	"net/textproto"
+	"os"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 type MockRWC struct {
@@ -59,6 +65,54 @@ func TestClientResponse(t *testing.T) {
 	}
 }
 
+func TestClientResponseMalformed(t *testing.T) {
+	tests := []struct {
+		Input   string
+		MaxSize int
+	}{
+		{"211-Truncated\r\nSecond line\r\n", 0},
+		{"211-Endless\r\n" + strings.Repeat("More\r\n", 100) + "211 End", 100},
+		{"200 " + strings.Repeat("x", DefaultMaxLineLength) + "\r\n", 0},
+	}
+	for i, tt := range tests {
+		client := &Client{
+			proto: textproto.NewConn(MockRWC{
+				R: bytes.NewBufferString(tt.Input),
+				W: new(bytes.Buffer),
+			}),
+			MaxReplySize: tt.MaxSize,
+		}
+		if reply, err := client.readResponse(); err == nil {
+			t.Errorf("tests[%d]: expected error (got %#v)", i, reply)
+		}
+	}
+}
+
+func TestClientMaxLineLength(t *testing.T) {
+	tests := []struct {
+		Input   string
+		MaxLen  int
+		Success bool
+	}{
+		{"200 " + strings.Repeat("x", 6) + "\r\n", 10, true},
+		{"200 " + strings.Repeat("x", 7) + "\r\n", 10, false},
+		{"200 " + strings.Repeat("x", 10000) + "\r\n", 0, true},
+		{"200 " + strings.Repeat("x", 10000) + "\r\n", 5000, false},
+	}
+	for i, tt := range tests {
+		client := &Client{
+			proto: textproto.NewConn(MockRWC{
+				R: bytes.NewBufferString(tt.Input),
+				W: new(bytes.Buffer),
+			}),
+			MaxLineLength: tt.MaxLen,
+		}
+		if _, err := client.readResponse(); (err == nil) != tt.Success {
+			t.Errorf("tests[%d]: expected success %t (got %v)", i, tt.Success, err)
+		}
+	}
+}
+
 func TestClientDo(t *testing.T) {
 	const (
 		expectedData = "NOOP\r\n"
@@ -88,3 +142,438 @@ func TestClientDo(t *testing.T) {
 		t.Errorf("Msg: %v (!= %v)", reply.Msg, expectedMsg)
 	}
 }
+
+func TestClientDoCancelableContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rwc := MockRWC{
+		R: bytes.NewBufferString("200 Okay\r\n"),
+		W: new(bytes.Buffer),
+	}
+	client := &Client{
+		proto: textproto.NewConn(rwc),
+	}
+	reply, err := client.Do(ctx, "NOOP")
+	if err != nil {
+		t.Fatal("error:", err)
+	}
+	if reply.Code != CodeOkay {
+		t.Errorf("Code: %v (!= %v)", reply.Code, CodeOkay)
+	}
+}
+
+func TestClientLogger(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	c, err := Dial(ctx, "tcp", s.ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var lines []string
+	c.Logger = func(dir Direction, line string) {
+		lines = append(lines, dir.String()+" "+line)
+	}
+	if err := c.Login(ctx, "user", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"--> USER user",
+		"<-- 331 Need password",
+		"--> PASS ****",
+		"<-- 230 Logged in",
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("lines = %q (expected %q)", lines, expected)
+	}
+}
+
+func TestLoginWithoutPassword(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	var user string
+	s.handle("USER", func(sc *serverConn, arg string) {
+		user = arg
+		sc.reply(230, "Logged in")
+	})
+	c, err := Dial(ctx, "tcp", s.ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := c.AnonymousLogin(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if user != "anonymous" {
+		t.Errorf("USER %s (expected USER anonymous)", user)
+	}
+	if n := s.count("PASS"); n != 0 {
+		t.Errorf("PASS sent %d times (expected 0)", n)
+	}
+}
+
+func TestLoginAfterWelcome(t *testing.T) {
+	c := &Client{Welcome: Reply{Code: CodeLoggedIn}}
+	if err := c.Login(context.Background(), "user", "secret"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDialWelcomeTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		// Accept, but never send a welcome message.
+		conn, err := ln.Accept()
+		if err == nil {
+			defer conn.Close()
+			io.Copy(io.Discard, conn)
+		}
+	}()
+
+	start := time.Now()
+	_, err = DialWithOptions(context.Background(), "tcp", ln.Addr().String(), DialOptions{
+		Dialer:         &net.Dialer{Timeout: time.Second},
+		WelcomeTimeout: 50 * time.Millisecond,
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Dial returned after %v", d)
+	}
+}
+
+func TestDialDefaultWelcomeTimeout(t *testing.T) {
+	defer func(d time.Duration) { DefaultWelcomeTimeout = d }(DefaultWelcomeTimeout)
+	DefaultWelcomeTimeout = 50 * time.Millisecond
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		// Accept, but never send a welcome message.
+		conn, err := ln.Accept()
+		if err == nil {
+			defer conn.Close()
+			io.Copy(io.Discard, conn)
+		}
+	}()
+
+	start := time.Now()
+	_, err = DialWithOptions(context.Background(), "tcp", ln.Addr().String(), DialOptions{})
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("err = %v (expected %v)", err, os.ErrDeadlineExceeded)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Dial returned after %v", d)
+	}
+}
+
+// proxyDialer dials directly, but reports the address of a proxy as the
+// remote address of its connections.
+type proxyDialer struct {
+	dialed []string
+}
+
+func (d *proxyDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	d.dialed = append(d.dialed, addr)
+	var nd net.Dialer
+	conn, err := nd.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return addrConn{conn, testAddr{"tcp", "192.0.2.1:1080"}}, nil
+}
+
+func TestDialProxy(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("hello.txt", []byte("Hello, World\n"))
+	d := new(proxyDialer)
+	c, err := DialWithOptions(ctx, "tcp", s.ln.Addr().String(), DialOptions{ProxyDialer: d})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.Login(ctx, "user", "pass"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.RetrieveFile(ctx, "hello.txt", io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.dialed) != 2 {
+		t.Errorf("dialed %q (expected control and data connection)", d.dialed)
+	}
+}
+
+func TestDialProxyLookup(t *testing.T) {
+	defer func(f func(context.Context, string, string) ([]net.IP, error)) { lookupIP = f }(lookupIP)
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("hello.txt", []byte("Hello, World\n"))
+	_, port, _ := net.SplitHostPort(s.ln.Addr().String())
+	addr := net.JoinHostPort("ftp.example.com", port)
+
+	tests := []struct {
+		IPs      []net.IP
+		Expected []string
+	}{
+		// Prefer IPv4 like the proxy connection.
+		{[]net.IP{net.IPv6loopback, net.IPv4(127, 0, 0, 1)}, []string{"EPSV", "EPSV"}},
+		// DNS only works through the proxy, so learn the IP from PASV.
+		{nil, []string{"PASV", "EPSV"}},
+	}
+	for i, tt := range tests {
+		lookupIP = func(ctx context.Context, network, host string) ([]net.IP, error) {
+			if tt.IPs == nil {
+				return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+			}
+			return tt.IPs, nil
+		}
+		d := &redirectDialer{addr: s.ln.Addr().String()}
+		c, err := DialWithOptions(ctx, "tcp", addr, DialOptions{ProxyDialer: d})
+		if err != nil {
+			t.Fatalf("tests[%d]: %v", i, err)
+		}
+		if err := c.Login(ctx, "user", "pass"); err != nil {
+			t.Fatal(err)
+		}
+		var sent []string
+		for j := 0; j < 2; j++ {
+			if _, err := c.RetrieveFile(ctx, "hello.txt", io.Discard); err != nil {
+				t.Fatalf("tests[%d]: %v", i, err)
+			}
+			sent = append(sent, c.LastDataAddr().Command)
+		}
+		c.Close()
+		if !reflect.DeepEqual(sent, tt.Expected) {
+			t.Errorf("tests[%d]: expected %q (got %q)", i, tt.Expected, sent)
+		}
+	}
+}
+
+// redirectDialer dials addr for connections to the FTP server, like a
+// proxy resolving the host name itself, and other addresses directly.
+type redirectDialer struct {
+	addr string
+}
+
+func (d *redirectDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if host, _, _ := net.SplitHostPort(addr); host == "ftp.example.com" {
+		addr = d.addr
+	}
+	var nd net.Dialer
+	conn, err := nd.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return addrConn{conn, testAddr{"tcp", "192.0.2.1:1080"}}, nil
+}
+
+func TestInvalidCommand(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("a.txt", nil)
+	c := s.dial(ctx)
+
+	for i, name := range []string{"a.txt\r\nDELE b.txt", "a.txt\nQUIT", "a.txt\x00"} {
+		if err := c.Delete(ctx, name); err != ErrInvalidCommand {
+			t.Errorf("tests[%d]: expected %v (got %v)", i, ErrInvalidCommand, err)
+		}
+	}
+	if _, err := c.RetrieveFile(ctx, "a.txt\r\nDELE a.txt", io.Discard); err != ErrInvalidCommand {
+		t.Errorf("RetrieveFile: expected %v (got %v)", ErrInvalidCommand, err)
+	}
+	if n := s.count("DELE"); n != 0 {
+		t.Errorf("DELE sent %d times (expected 0)", n)
+	}
+	if err := c.NoOp(ctx); err != nil {
+		t.Error("client unusable after invalid command:", err)
+	}
+}
+
+func TestReinitialize(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("hello.txt", []byte("Hello, World\n"))
+	c := s.dial(ctx)
+
+	if _, err := c.RetrieveFile(ctx, "hello.txt", io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Reinitialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if c.dataType != "" || c.username != "" {
+		t.Errorf("session state not cleared")
+	}
+	if err := c.Login(ctx, "other", "pass"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.RetrieveFile(ctx, "hello.txt", io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.count("TYPE"); n != 2 {
+		t.Errorf("TYPE sent %d times (expected 2)", n)
+	}
+}
+
+func TestClientDoCmd(t *testing.T) {
+	ctx := context.Background()
+	rwc := MockRWC{
+		R: bytes.NewBufferString("200 Okay\r\n"),
+		W: new(bytes.Buffer),
+	}
+	client := &Client{
+		proto: textproto.NewConn(rwc),
+	}
+	if _, err := client.DoCmd(ctx, "SITE", "CHMOD", "644", "my file.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "SITE CHMOD 644 my file.txt\r\n"; rwc.W.String() != expected {
+		t.Errorf("Sent: %q (!= %q)", rwc.W.String(), expected)
+	}
+	rwc.R.WriteString("213 abc\r\n")
+	rwc.W.Reset()
+	if _, err := client.DoCmd(ctx, "XSHA256", "a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "XSHA256 a.txt\r\n"; rwc.W.String() != expected {
+		t.Errorf("Sent: %q (!= %q)", rwc.W.String(), expected)
+	}
+
+	tests := []struct {
+		Verb string
+		Args []string
+	}{
+		{"", nil},
+		{"DELE x\r\nQUIT", nil},
+		{"DELE", []string{"a.txt\r\nQUIT"}},
+		{"DELE", []string{"a.txt", "\x00"}},
+		{"DELE", []string{"a\tb"}},
+		{"X-SHA", nil},
+		{"XSHA 256", nil},
+	}
+	for i, tt := range tests {
+		rwc.W.Reset()
+		if _, err := client.DoCmd(ctx, tt.Verb, tt.Args...); !errors.Is(err, ErrInvalidCommand) {
+			t.Errorf("tests[%d]: expected %v (got %v)", i, ErrInvalidCommand, err)
+		}
+		if rwc.W.Len() != 0 {
+			t.Errorf("tests[%d]: sent %q", i, rwc.W.String())
+		}
+	}
+}
+
+func TestCloseGracefully(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	quit := make(chan struct{})
+	s.handle("QUIT", func(sc *serverConn, arg string) {
+		<-quit // never reply
+	})
+	defer close(quit)
+	c := s.dial(ctx)
+
+	ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if err := c.CloseGracefully(ctx); err != nil {
+		t.Error(err)
+	}
+	if err := c.NoOp(context.Background()); err == nil {
+		t.Error("connection not closed")
+	}
+}
+
+func TestClientReadReply(t *testing.T) {
+	ctx := context.Background()
+	rwc := MockRWC{
+		R: bytes.NewBufferString("421 Idle timeout\r\n"),
+		W: new(bytes.Buffer),
+	}
+	client := &Client{
+		proto: textproto.NewConn(rwc),
+	}
+	reply, err := client.ReadReply(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Code != CodeServiceNotAvailable {
+		t.Errorf("Code: %v (!= %v)", reply.Code, CodeServiceNotAvailable)
+	}
+	if rwc.W.Len() != 0 {
+		t.Errorf("Sent: %q", rwc.W.String())
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	s := newTestServer(t)
+	c := s.dial(context.Background())
+	if _, err := c.ReadReply(ctx); err != context.DeadlineExceeded {
+		t.Errorf("err = %v (expected %v)", err, context.DeadlineExceeded)
+	}
+}
+
+func TestClientOpts(t *testing.T) {
+	ctx := context.Background()
+	rwc := MockRWC{
+		R: bytes.NewBufferString("200 Okay\r\n"),
+		W: new(bytes.Buffer),
+	}
+	client := &Client{
+		proto: textproto.NewConn(rwc),
+	}
+	if _, err := client.Opts(ctx, "RETR RESUME"); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "OPTS RETR RESUME\r\n"; rwc.W.String() != expected {
+		t.Errorf("Sent: %q (!= %q)", rwc.W.String(), expected)
+	}
+	if _, err := client.Opts(ctx, "UTF8 ON\r\nDELE a.txt"); err != ErrInvalidCommand {
+		t.Errorf("err = %v (expected %v)", err, ErrInvalidCommand)
+	}
+}
+
+func TestSetClientName(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	var name string
+	s.handle("CLNT", func(sc *serverConn, arg string) {
+		name = arg
+		sc.reply(200, "Noted")
+	})
+	c := s.dial(ctx)
+
+	if err := c.SetClientName(ctx, "MyClient 1.0"); err != nil {
+		t.Fatal(err)
+	}
+	if name != "MyClient 1.0" {
+		t.Errorf("CLNT %s (expected CLNT MyClient 1.0)", name)
+	}
+
+	// A rejected name can be detected, but leaves the client usable.
+	for _, code := range []int{502, 504, 550} {
+		s.handle("CLNT", func(sc *serverConn, arg string) {
+			sc.reply(code, "Rejected")
+		})
+		if err := c.SetClientName(ctx, "MyClient 1.0"); !errors.Is(err, ErrUnsupported) {
+			t.Errorf("%d reply: err = %v (expected %v)", code, err, ErrUnsupported)
+		}
+	}
+	if err := c.NoOp(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Close()
+	if err := c.SetClientName(ctx, "MyClient 1.0"); err == nil {
+		t.Error("expected error on closed connection")
+	}
+}
@@ -0,0 +1,24 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"context"
+	"io"
+)
+
+// A Commander sends commands and opens data transfers. It is implemented by
+// *Client, so code that only needs these primitives can depend on
+// a Commander and be tested with a stub instead of a server.
+type Commander interface {
+	// Do sends a command and returns the reply.
+	Do(ctx context.Context, command string) (Reply, error)
+
+	// Text and Binary send a command that transfers data and return the
+	// reply and the data connection in ASCII and image mode respectively.
+	// Closing the data connection reads the completion reply.
+	Text(ctx context.Context, command string) (Reply, io.ReadWriteCloser, error)
+	Binary(ctx context.Context, command string) (Reply, io.ReadWriteCloser, error)
+}
+
+var _ Commander = (*Client)(nil)
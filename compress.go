@@ -0,0 +1,109 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"bufio"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// SetCompression enables MODE Z, which compresses data transfers using
+// zlib, if level is non-zero. The level ranges from zlib.BestSpeed to
+// zlib.BestCompression, or is zlib.DefaultCompression. The level is also
+// proposed to the server using OPTS MODE Z; servers that don't support
+// setting it use their default. A level of zero resets the transfer mode to
+// MODE S. If the server doesn't advertise MODE Z, an error wrapping
+// ErrUnsupported is returned.
+func (c *Client) SetCompression(ctx context.Context, level int) error {
+	if level == 0 {
+		if err := c.simpleCommand(ctx, "MODE S"); err != nil {
+			return err
+		}
+		c.compressLevel = 0
+		return nil
+	}
+	if level < zlib.DefaultCompression || level > zlib.BestCompression {
+		return fmt.Errorf("ftp: invalid compression level %d", level)
+	}
+	features, err := c.Features(ctx)
+	if err != nil {
+		return err
+	} else if !strings.EqualFold(features.Param("MODE"), "Z") {
+		return unsupported("MODE Z")
+	}
+	if err := c.simpleCommand(ctx, "MODE Z"); err != nil {
+		return err
+	}
+	c.compressLevel = level
+	if level != zlib.DefaultCompression {
+		if _, err := c.sendCommand(ctx, "OPTS MODE Z LEVEL="+strconv.Itoa(level)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compress wraps a data connection in zlib compression if MODE Z is enabled.
+func (c *Client) compress(rwc io.ReadWriteCloser) io.ReadWriteCloser {
+	if c.compressLevel == 0 {
+		return rwc
+	}
+	return &zlibConn{rwc: rwc, level: c.compressLevel}
+}
+
+// zlibConn compresses the data written to and decompresses the data read
+// from a data connection. The zlib reader and writer are created on first
+// use, because a data connection is used in one direction only.
+type zlibConn struct {
+	rwc   io.ReadWriteCloser
+	level int
+	r     io.ReadCloser
+	w     *zlib.Writer
+}
+
+func (z *zlibConn) Read(p []byte) (int, error) {
+	if z.r == nil {
+		// An empty transfer may have no zlib stream at all.
+		br := bufio.NewReader(z.rwc)
+		if _, err := br.Peek(1); err != nil {
+			return 0, err
+		}
+		r, err := zlib.NewReader(br)
+		if err != nil {
+			return 0, err
+		}
+		z.r = r
+	}
+	return z.r.Read(p)
+}
+
+func (z *zlibConn) Write(p []byte) (int, error) {
+	if z.w == nil {
+		w, err := zlib.NewWriterLevel(z.rwc, z.level)
+		if err != nil {
+			return 0, err
+		}
+		z.w = w
+	}
+	return z.w.Write(p)
+}
+
+// Close flushes the compressed data and closes the data connection.
+func (z *zlibConn) Close() error {
+	var err error
+	if z.w != nil {
+		err = z.w.Close()
+	}
+	if z.r != nil {
+		z.r.Close()
+	}
+	if cerr := z.rwc.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
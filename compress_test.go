@@ -0,0 +1,64 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCompression(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFeatures("MODE Z")
+	expected := strings.Repeat("Hello, World\n", 100)
+	c := s.dial(ctx)
+
+	if err := c.SetCompression(ctx, 9); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.StoreFile(ctx, "hello.txt", strings.NewReader(expected)); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := s.file("hello.txt"); string(b) != expected {
+		t.Errorf("stored = %q (expected %q)", b, expected)
+	}
+	var buf bytes.Buffer
+	if _, err := c.RetrieveFile(ctx, "hello.txt", &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != expected {
+		t.Errorf("data = %q (expected %q)", buf.String(), expected)
+	}
+
+	// Empty transfers have no zlib stream.
+	s.setFile("empty.txt", nil)
+	buf.Reset()
+	if _, err := c.RetrieveFile(ctx, "empty.txt", &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.SetCompression(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if _, err := c.RetrieveFile(ctx, "hello.txt", &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != expected {
+		t.Errorf("data = %q (expected %q)", buf.String(), expected)
+	}
+}
+
+func TestCompressionUnsupported(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	c := s.dial(ctx)
+
+	if err := c.SetCompression(ctx, -1); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("err = %v (expected %v)", err, ErrUnsupported)
+	}
+}
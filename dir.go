@@ -0,0 +1,101 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+)
+
+// ChangeDir changes the working directory to path.
+func (c *Client) ChangeDir(ctx context.Context, path string) error {
+	if err := c.simpleCommand(ctx, "CWD "+path); err != nil {
+		return err
+	}
+	c.trackDir(ctx, path)
+	return nil
+}
+
+// ChangeDirToParent changes the working directory to its parent.
+func (c *Client) ChangeDirToParent(ctx context.Context) error {
+	if err := c.simpleCommand(ctx, "CDUP"); err != nil {
+		return err
+	}
+	c.trackDir(ctx, "..")
+	return nil
+}
+
+// trackDir records the working directory after changing it to p, so
+// Reconnect can restore it. If the previous directory is unknown and p is
+// relative, the directory is requested using PWD.
+func (c *Client) trackDir(ctx context.Context, p string) {
+	switch {
+	case path.IsAbs(p):
+		c.dir = path.Clean(p)
+	case c.dir != "":
+		c.dir = path.Join(c.dir, p)
+	default:
+		c.CurrentDir(ctx)
+	}
+}
+
+// CurrentDir returns the working directory.
+func (c *Client) CurrentDir(ctx context.Context) (string, error) {
+	reply, err := c.sendCommand(ctx, "PWD")
+	if err != nil {
+		return "", err
+	} else if reply.Code != CodeCreated {
+		return "", reply
+	}
+	name, ok := parsePathname(reply.Msg)
+	if !ok {
+		return "", errors.New("ftp: PWD reply provided no pathname")
+	}
+	c.dir = name
+	return name, nil
+}
+
+// MakeDir creates the directory at path.
+// It returns the pathname of the created directory as reported by the server
+// in the 257 reply, which is usually absolute, or an empty string if the
+// server didn't report it. Doubled quotes in the pathname are unescaped
+// like in CurrentDir.
+func (c *Client) MakeDir(ctx context.Context, path string) (string, error) {
+	reply, err := c.sendCommand(ctx, "MKD "+path)
+	if err != nil {
+		return "", err
+	} else if !reply.PositiveComplete() {
+		return "", reply
+	}
+	name, _ := parsePathname(reply.Msg)
+	return name, nil
+}
+
+// RemoveDir removes the directory at path.
+func (c *Client) RemoveDir(ctx context.Context, path string) error {
+	return c.simpleCommand(ctx, "RMD "+path)
+}
+
+// parsePathname parses the quoted pathname from a 257 reply as defined in
+// RFC 959 Appendix II. Quotes within the pathname are doubled.
+func parsePathname(msg string) (string, bool) {
+	if !strings.HasPrefix(msg, `"`) {
+		return "", false
+	}
+	var b strings.Builder
+	for i := 1; i < len(msg); i++ {
+		if msg[i] != '"' {
+			b.WriteByte(msg[i])
+			continue
+		}
+		if i+1 < len(msg) && msg[i+1] == '"' {
+			b.WriteByte('"')
+			i++
+			continue
+		}
+		return b.String(), true
+	}
+	return "", false
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParsePathname(t *testing.T) {
+	tests := []struct {
+		Input string
+		Name  string
+		OK    bool
+	}{
+		{`"/usr/dm" created`, "/usr/dm", true},
+		{`"/usr/dm/""quoted""" created`, `/usr/dm/"quoted"`, true},
+		{`"/usr/dm"`, "/usr/dm", true},
+		{`Directory created`, "", false},
+		{`"/unterminated`, "", false},
+	}
+	for i, tt := range tests {
+		name, ok := parsePathname(tt.Input)
+		if name != tt.Name || ok != tt.OK {
+			t.Errorf("tests[%d]: expected %q, %v (got %q, %v)", i, tt.Name, tt.OK, name, ok)
+		}
+	}
+}
+
+func TestChangeDir(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	c := s.dial(ctx)
+
+	if err := c.ChangeDir(ctx, "pub/incoming"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ChangeDirToParent(ctx); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := c.CurrentDir(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir != "/pub" {
+		t.Errorf("dir = %q (expected %q)", dir, "/pub")
+	}
+}
+
+func TestMakeDir(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	c := s.dial(ctx)
+
+	tests := []struct {
+		Reply string
+		Name  string
+	}{
+		{`"/home/user/new" created`, "/home/user/new"},
+		{`"/home/user/say ""hi""" created`, `/home/user/say "hi"`},
+		{"Directory created", ""},
+	}
+	for i, tt := range tests {
+		s.handle("MKD", func(sc *serverConn, arg string) {
+			sc.reply(257, "%s", tt.Reply)
+		})
+		name, err := c.MakeDir(ctx, "new")
+		if err != nil {
+			t.Errorf("tests[%d] error: %v", i, err)
+			continue
+		}
+		if name != tt.Name {
+			t.Errorf("tests[%d]: expected %q (got %q)", i, tt.Name, name)
+		}
+	}
+
+	s.handle("MKD", func(sc *serverConn, arg string) {
+		sc.reply(550, "Permission denied")
+	})
+	if _, err := c.MakeDir(ctx, "new"); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -0,0 +1,29 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+// An Encoding converts text between UTF-8 and the character set of a server
+// that doesn't support UTF-8, like Shift-JIS or CP1251. The character set
+// must be ASCII compatible, because it is applied to whole command and reply
+// lines. The encodings in golang.org/x/text/encoding can be adapted using
+// the String methods of their encoders and decoders.
+type Encoding interface {
+	// Encode converts s from UTF-8 to the server's character set.
+	Encode(s string) (string, error)
+	// Decode converts s from the server's character set to UTF-8.
+	Decode(s string) (string, error)
+}
+
+func (c *Client) encode(s string) (string, error) {
+	if c.Encoding == nil {
+		return s, nil
+	}
+	return c.Encoding.Encode(s)
+}
+
+func (c *Client) decode(s string) (string, error) {
+	if c.Encoding == nil {
+		return s, nil
+	}
+	return c.Encoding.Decode(s)
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// latin1 is the ISO 8859-1 encoding.
+type latin1 struct{}
+
+func (latin1) Encode(s string) (string, error) {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r > 0xff {
+			return "", errors.New("latin1: rune out of range")
+		}
+		b = append(b, byte(r))
+	}
+	return string(b), nil
+}
+
+func (latin1) Decode(s string) (string, error) {
+	r := make([]rune, len(s))
+	for i := 0; i < len(s); i++ {
+		r[i] = rune(s[i])
+	}
+	return string(r), nil
+}
+
+func TestEncoding(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("caf\xe9.txt", []byte("Hello, World\n"))
+	c := s.dial(ctx)
+	c.Encoding = latin1{}
+
+	names, err := c.NameList(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"café.txt"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("names = %q (expected %q)", names, expected)
+	}
+	if _, err := c.RetrieveFile(ctx, "café.txt", io.Discard); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrUnsupported is returned when the server does not support an extension.
+var ErrUnsupported = errors.New("not supported by server")
+
+func unsupported(feature string) error {
+	return fmt.Errorf("ftp: %s: %w", feature, ErrUnsupported)
+}
+
+// isUnsupported reports whether reply indicates the command is not implemented.
+func isUnsupported(reply Reply) bool {
+	return reply.Code == CodeUnrecognizedCommand || reply.Code == CodeNotImplemented
+}
+
+// Features is the set of extensions advertised by the server in its FEAT
+// reply as defined in RFC 2389. It maps the upper-case feature name to its
+// parameters, like "STREAM" for "REST STREAM".
+type Features map[string]string
+
+// Supports reports whether the feature is advertised.
+func (f Features) Supports(name string) bool {
+	_, ok := f[strings.ToUpper(name)]
+	return ok
+}
+
+// Param returns the parameters of the feature, or an empty string if the
+// feature has no parameters or isn't advertised.
+func (f Features) Param(name string) string {
+	return f[strings.ToUpper(name)]
+}
+
+// Features returns the features advertised by the server using FEAT.
+// A server that doesn't implement FEAT has no features. The result is cached,
+// so only the first call sends the command.
+func (c *Client) Features(ctx context.Context) (Features, error) {
+	if c.features != nil {
+		return c.features, nil
+	}
+	reply, err := c.sendCommand(ctx, "FEAT")
+	if err != nil {
+		return nil, err
+	}
+	c.features = make(Features)
+	if reply.PositiveComplete() {
+		c.features = parseFeatures(reply.Lines())
+	}
+	return c.features, nil
+}
+
+// parseFeatures parses the lines of a FEAT reply:
+//
+//	Extensions supported:
+//	 MDTM
+//	 REST STREAM
+//	End
+func parseFeatures(lines []string) Features {
+	features := make(Features)
+	if len(lines) < 3 {
+		return features
+	}
+	for _, line := range lines[1 : len(lines)-1] {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		name, param := line, ""
+		if i := strings.IndexByte(line, ' '); i >= 0 {
+			name, param = line[:i], strings.TrimSpace(line[i+1:])
+		}
+		features[strings.ToUpper(name)] = param
+	}
+	return features
+}
+
+// supports reports whether the server advertises feature.
+func (c *Client) supports(ctx context.Context, feature string) (bool, error) {
+	features, err := c.Features(ctx)
+	if err != nil {
+		return false, err
+	}
+	return features.Supports(feature), nil
+}
+
+// EnableUTF8 enables UTF-8 encoded pathnames using OPTS UTF8 ON as described
+// in RFC 2640. Without it, servers may send pathnames, for example in MLSD
+// and NLST listings, in Latin-1 or their local encoding. If the server
+// doesn't advertise UTF8, an error wrapping ErrUnsupported is returned.
+func (c *Client) EnableUTF8(ctx context.Context) error {
+	if ok, err := c.supports(ctx, "UTF8"); err != nil {
+		return err
+	} else if !ok {
+		return unsupported("UTF8")
+	}
+	return c.simpleCommand(ctx, "OPTS UTF8 ON")
+}
+
+// System returns the operating system of the server using SYST,
+// like "UNIX Type: L8" or "Windows_NT". The result is cached,
+// so only the first call sends the command.
+func (c *Client) System(ctx context.Context) (string, error) {
+	if c.system != "" {
+		return c.system, nil
+	}
+	reply, err := c.sendCommand(ctx, "SYST")
+	if err != nil {
+		return "", err
+	} else if reply.Code != CodeSystemType {
+		return "", reply
+	}
+	c.system = reply.Msg
+	return c.system, nil
+}
+
+// ServerSoftware identifies the server software as announced in the welcome
+// message. Product and Version are empty if they aren't recognized.
+type ServerSoftware struct {
+	Product string // like "ProFTPD" or "vsFTPd"
+	Version string // like "1.3.5"
+	Banner  string // the complete welcome message
+}
+
+// serverProducts are the server products recognized in welcome messages.
+var serverProducts = []string{
+	"ProFTPD",
+	"vsFTPd",
+	"Pure-FTPd",
+	"FileZilla Server",
+	"Microsoft FTP Service",
+	"Serv-U",
+	"wu-ftpd",
+	"glFTPd",
+	"CrushFTP",
+	"pyftpdlib",
+}
+
+var bannerRegexp = func() *regexp.Regexp {
+	names := make([]string, len(serverProducts))
+	for i, name := range serverProducts {
+		names[i] = regexp.QuoteMeta(name)
+	}
+	return regexp.MustCompile(`(?i)\b(` + strings.Join(names, "|") + `)` +
+		`(?:[\s/(]+(?:FTP Server\s+)?(?:version\s+|v)?([0-9][\w.-]*))?`)
+}()
+
+// ServerSoftware returns the server software as guessed from the welcome
+// message. The result is best effort: many servers can be configured to
+// hide their identity, and any server may claim to be another.
+func (c *Client) ServerSoftware() ServerSoftware {
+	return parseBanner(c.Welcome.Msg)
+}
+
+func parseBanner(banner string) ServerSoftware {
+	sw := ServerSoftware{Banner: banner}
+	m := bannerRegexp.FindStringSubmatch(banner)
+	if m == nil {
+		return sw
+	}
+	for _, name := range serverProducts {
+		if strings.EqualFold(name, m[1]) {
+			sw.Product = name
+		}
+	}
+	sw.Version = strings.TrimRight(m[2], ".-")
+	return sw
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseFeatures(t *testing.T) {
+	expected := Features{
+		"MDTM": "",
+		"REST": "STREAM",
+		"AUTH": "TLS;SSL",
+		"MLST": "type*;size*;modify*;",
+		"UTF8": "",
+	}
+	features := parseFeatures([]string{"Extensions supported:", " MDTM", " REST STREAM", " AUTH TLS;SSL", " mlst type*;size*;modify*;", " UTF8", "End"})
+	if !reflect.DeepEqual(features, expected) {
+		t.Errorf("features = %#v (expected %#v)", features, expected)
+	}
+	if !features.Supports("rest") || features.Param("REST") != "STREAM" {
+		t.Errorf("REST not supported")
+	}
+	if features.Supports("MFMT") {
+		t.Errorf("MFMT supported")
+	}
+}
+
+func TestFeaturesCached(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	c := s.dial(ctx)
+
+	for i := 0; i < 2; i++ {
+		features, err := c.Features(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !features.Supports("MLST") {
+			t.Errorf("MLST not supported")
+		}
+	}
+	if n := s.count("FEAT"); n != 1 {
+		t.Errorf("FEAT sent %d times (expected 1)", n)
+	}
+}
+
+func TestEnableUTF8(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	c := s.dial(ctx)
+
+	if err := c.EnableUTF8(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.count("OPTS"); n != 1 {
+		t.Errorf("OPTS sent %d times (expected 1)", n)
+	}
+
+	s.setFeatures()
+	c = s.dial(ctx)
+	if err := c.EnableUTF8(ctx); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("err = %v (expected %v)", err, ErrUnsupported)
+	}
+}
+
+func TestSystem(t *testing.T) {
+	const expected = "UNIX Type: L8"
+
+	ctx := context.Background()
+	s := newTestServer(t)
+	c := s.dial(ctx)
+
+	for i := 0; i < 2; i++ {
+		system, err := c.System(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if system != expected {
+			t.Errorf("system = %q (expected %q)", system, expected)
+		}
+	}
+	if n := s.count("SYST"); n != 1 {
+		t.Errorf("SYST sent %d times (expected 1)", n)
+	}
+}
+
+func TestParseBanner(t *testing.T) {
+	tests := []struct {
+		Banner           string
+		Product, Version string
+	}{
+		{"ProFTPD 1.3.5e Server (Debian) [::ffff:192.0.2.1]", "ProFTPD", "1.3.5e"},
+		{"(vsFTPd 3.0.3)", "vsFTPd", "3.0.3"},
+		{"FileZilla Server version 0.9.60 beta", "FileZilla Server", "0.9.60"},
+		{"FileZilla Server 1.7.0", "FileZilla Server", "1.7.0"},
+		{"---------- Welcome to Pure-FTPd [privsep] [TLS] ----------\nYou are user number 1 of 50 allowed.", "Pure-FTPd", ""},
+		{"Microsoft FTP Service", "Microsoft FTP Service", ""},
+		{"Serv-U FTP Server v15.1 ready...", "Serv-U", "15.1"},
+		{"Service ready", "", ""},
+	}
+	for i, tt := range tests {
+		expected := ServerSoftware{Product: tt.Product, Version: tt.Version, Banner: tt.Banner}
+		if sw := parseBanner(tt.Banner); sw != expected {
+			t.Errorf("tests[%d]: expected %#v (got %#v)", i, expected, sw)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetrieveFile retrieves the file at path in image mode and copies it to w.
+// It returns the number of bytes written to w. The data connection is always
+// closed and the completion reply is read, even if the copy fails, so the
+// Client remains usable afterwards.
+func (c *Client) RetrieveFile(ctx context.Context, path string, w io.Writer) (int64, error) {
+	return c.RetrieveFileFrom(ctx, path, 0, w)
+}
+
+// RetrieveFileFrom is like RetrieveFile, but resumes the transfer at offset
+// using REST. If the server rejects REST, an error wrapping
+// ErrRestartRejected is returned, so the caller can fall back to retrieving
+// the whole file.
+func (c *Client) RetrieveFileFrom(ctx context.Context, path string, offset int64, w io.Writer) (int64, error) {
+	return c.retrieve(ctx, path, offset, w, nil)
+}
+
+// retrieve implements RetrieveFileFrom. If started isn't nil, it is called
+// with the preliminary reply before the data is copied.
+func (c *Client) retrieve(ctx context.Context, path string, offset int64, w io.Writer, started func(Reply)) (int64, error) {
+	size := int64(-1)
+	if c.VerifySize {
+		var err error
+		if size, err = c.Size(ctx, path); errors.Is(err, ErrUnsupported) {
+			size = -1
+		} else if err != nil {
+			return 0, err
+		}
+	}
+	reply, conn, err := c.transfer(ctx, "RETR "+path, "I", offset)
+	if err != nil {
+		return 0, err
+	}
+	if started != nil {
+		started(reply)
+	}
+	n, err := c.copy(w, conn)
+	if cerr := conn.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && size >= 0 && offset+n != size {
+		err = fmt.Errorf("%w: received %d of %d bytes", ErrSizeMismatch, offset+n, size)
+	}
+	return n, err
+}
+
+// ErrSizeMismatch is returned if VerifySize is set and the size of
+// a retrieved file differs from the size reported by the server.
+var ErrSizeMismatch = errors.New("ftp: size mismatch")
+
+// RetrieveReader retrieves the file at path in image mode. The caller must
+// close the returned reader, which reads the completion reply, before
+// sending other commands.
+func (c *Client) RetrieveReader(ctx context.Context, path string) (io.ReadCloser, error) {
+	_, conn, err := c.transfer(ctx, "RETR "+path, "I", 0)
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{conn, conn}, nil
+}
+
+// StoreWriter stores the data written to the returned writer in image mode
+// as the file at path. The caller must close the writer, which completes the
+// transfer and reads the completion reply, before sending other commands.
+func (c *Client) StoreWriter(ctx context.Context, path string) (io.WriteCloser, error) {
+	_, conn, err := c.transfer(ctx, "STOR "+path, "I", 0)
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		io.Writer
+		io.Closer
+	}{conn, conn}, nil
+}
+
+// StoreFile stores the contents of r in image mode as the file at path.
+// It returns the number of bytes read from r. The data connection is always
+// closed and the completion reply is read, even if the copy fails.
+//
+// If AllocateOnStore is set and r implements io.Seeker, the number of bytes
+// to store is announced using Allocate first. If the server rejects ALLO,
+// for example because it doesn't implement it, the file is stored anyway.
+func (c *Client) StoreFile(ctx context.Context, path string, r io.Reader) (int64, error) {
+	if s, ok := r.(io.Seeker); ok && c.AllocateOnStore {
+		if err := c.allocateRemaining(ctx, s); err != nil {
+			return 0, err
+		}
+	}
+	return c.store(ctx, "STOR "+path, 0, r)
+}
+
+// allocateRemaining announces the number of bytes from the current offset
+// of s to its end using Allocate. It only returns an error if s can't be
+// restored to its offset or ALLO fails other than by a negative reply,
+// so seekers that can't seek, like pipes, are stored without ALLO.
+func (c *Client) allocateRemaining(ctx context.Context, s io.Seeker) error {
+	cur, err := s.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil
+	}
+	end, err := s.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil
+	}
+	if _, err := s.Seek(cur, io.SeekStart); err != nil {
+		return err
+	}
+	var reply Reply
+	if err := c.Allocate(ctx, end-cur); err != nil && !errors.As(err, &reply) {
+		return err
+	}
+	return nil
+}
+
+// Allocate asks the server to reserve size bytes for the next file stored
+// using ALLO. Servers that don't need it reply with CodeSuperfluous,
+// which is not an error; servers that don't implement it return a Reply
+// with a permanent negative code.
+func (c *Client) Allocate(ctx context.Context, size int64) error {
+	return c.simpleCommand(ctx, "ALLO "+strconv.FormatInt(size, 10))
+}
+
+// StoreFileFrom resumes storing the file at path at offset, for example the
+// remote Size of an interrupted upload. The reader is positioned at offset
+// by seeking if it implements io.Seeker, or by discarding offset bytes
+// otherwise. It returns the number of bytes stored starting at offset.
+//
+// The upload is resumed using REST followed by STOR. Some servers, like
+// older IIS and many embedded servers, don't support REST for uploads.
+// In that case the remote size is compared to offset and, if they match,
+// the remainder is appended using APPE.
+func (c *Client) StoreFileFrom(ctx context.Context, path string, offset int64, r io.Reader) (int64, error) {
+	if s, ok := r.(io.Seeker); ok {
+		if _, err := s.Seek(offset, io.SeekStart); err != nil {
+			return 0, err
+		}
+	} else if _, err := io.CopyN(io.Discard, r, offset); err != nil {
+		return 0, err
+	}
+
+	n, err := c.store(ctx, "STOR "+path, offset, r)
+	if !errors.Is(err, ErrRestartRejected) {
+		return n, err
+	}
+	size, serr := c.Size(ctx, path)
+	if serr != nil || size != offset {
+		return 0, err
+	}
+	return c.store(ctx, "APPE "+path, 0, r)
+}
+
+// store sends a command in image mode and copies r to the data connection.
+func (c *Client) store(ctx context.Context, command string, offset int64, r io.Reader) (int64, error) {
+	_, conn, err := c.transfer(ctx, command, "I", offset)
+	if err != nil {
+		return 0, err
+	}
+	n, err := c.copy(conn, r)
+	if cerr := conn.Close(); err == nil {
+		err = cerr
+	}
+	return n, err
+}
+
+// Delete deletes the file at path.
+func (c *Client) Delete(ctx context.Context, path string) error {
+	return c.simpleCommand(ctx, "DELE "+path)
+}
+
+// Rename renames the file or directory from to to.
+// RNTO is only sent if the server accepts RNFR with a pending reply.
+func (c *Client) Rename(ctx context.Context, from, to string) error {
+	reply, err := c.sendCommand(ctx, "RNFR "+from)
+	if err != nil {
+		return err
+	} else if reply.Code != CodePendingInformation {
+		return reply
+	}
+	return c.simpleCommand(ctx, "RNTO "+to)
+}
+
+// Size returns the size in bytes of the file at path using the SIZE command
+// defined in RFC 3659. The size is requested in image mode.
+// If the file doesn't exist, a Reply with CodeFileUnavailable is returned.
+// If the server doesn't implement SIZE, an error wrapping ErrUnsupported is returned.
+func (c *Client) Size(ctx context.Context, path string) (int64, error) {
+	if err := c.setType(ctx, "I"); err != nil {
+		return 0, err
+	}
+	reply, err := c.sendCommand(ctx, "SIZE "+path)
+	if err != nil {
+		return 0, err
+	} else if isUnsupported(reply) {
+		return 0, unsupported("SIZE")
+	} else if reply.Code != CodeFileStatus {
+		return 0, reply
+	}
+	return strconv.ParseInt(strings.TrimSpace(reply.Msg), 10, 64)
+}
+
+// ModTime returns the modification time of the file at path using the MDTM
+// command defined in RFC 3659. The time is returned in UTC.
+func (c *Client) ModTime(ctx context.Context, path string) (time.Time, error) {
+	reply, err := c.sendCommand(ctx, "MDTM "+path)
+	if err != nil {
+		return time.Time{}, err
+	} else if reply.Code != CodeFileStatus {
+		return time.Time{}, reply
+	}
+	return parseTimeVal(strings.TrimSpace(reply.Msg))
+}
+
+// SetModTime sets the modification time of the file at path using the MFMT
+// command. If the server doesn't advertise MFMT, an error wrapping
+// ErrUnsupported is returned.
+func (c *Client) SetModTime(ctx context.Context, path string, t time.Time) error {
+	if ok, err := c.supports(ctx, "MFMT"); err != nil {
+		return err
+	} else if !ok {
+		return unsupported("MFMT")
+	}
+	return c.simpleCommand(ctx, "MFMT "+t.UTC().Format("20060102150405")+" "+path)
+}
+
+// Append appends the contents of r in image mode to the file at path,
+// creating it if it doesn't exist. It returns the number of bytes read from r.
+func (c *Client) Append(ctx context.Context, path string, r io.Reader) (int64, error) {
+	return c.store(ctx, "APPE "+path, 0, r)
+}
+
+// Chmod changes the permissions of the file at path using SITE CHMOD,
+// the only portable way to do so on most Unix servers.
+// Only the permission bits of mode are sent.
+func (c *Client) Chmod(ctx context.Context, path string, mode os.FileMode) error {
+	reply, err := c.Site(ctx, "CHMOD "+strconv.FormatUint(uint64(mode.Perm()), 8)+" "+path)
+	if err != nil {
+		return err
+	} else if !reply.PositiveComplete() {
+		return reply
+	}
+	return nil
+}
+
+// FileExists reports whether a file or directory exists at path using MLST,
+// or SIZE if the server doesn't support MLST. Because SIZE only applies to
+// files, directories are reported as not existing by servers without MLST.
+//
+// A reply with CodeFileUnavailable means the path doesn't exist, unless its
+// message indicates that permission was denied, in which case the reply is
+// returned as error. Other failures are returned as error as well.
+func (c *Client) FileExists(ctx context.Context, path string) (bool, error) {
+	_, err := c.MLST(ctx, path)
+	if errors.Is(err, ErrUnsupported) {
+		_, err = c.Size(ctx, path)
+	}
+	if reply, ok := err.(Reply); ok && reply.Code == CodeFileUnavailable && !permissionDenied(reply) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// permissionDenied reports whether the message of reply indicates
+// that permission was denied.
+func permissionDenied(reply Reply) bool {
+	msg := strings.ToLower(reply.Msg)
+	return strings.Contains(msg, "permission") || strings.Contains(msg, "denied")
+}
@@ -0,0 +1,558 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetrieveFile(t *testing.T) {
+	const expected = "Hello, World\n"
+
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("hello.txt", []byte(expected))
+	c := s.dial(ctx)
+
+	var buf bytes.Buffer
+	n, err := c.RetrieveFile(ctx, "hello.txt", &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(expected)) {
+		t.Errorf("n = %d (expected %d)", n, len(expected))
+	}
+	if buf.String() != expected {
+		t.Errorf("data = %q (expected %q)", buf.String(), expected)
+	}
+
+	if _, err := c.RetrieveFile(ctx, "missing.txt", &buf); err == nil {
+		t.Error("expected error for missing file")
+	}
+	if _, err := c.Do(ctx, "NOOP"); err != nil {
+		t.Error("client unusable after transfer:", err)
+	}
+}
+
+func TestStoreFile(t *testing.T) {
+	const expected = "Hello, World\n"
+
+	ctx := context.Background()
+	s := newTestServer(t)
+	c := s.dial(ctx)
+
+	n, err := c.StoreFile(ctx, "hello.txt", bytes.NewBufferString(expected))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(expected)) {
+		t.Errorf("n = %d (expected %d)", n, len(expected))
+	}
+	if b, _ := s.file("hello.txt"); string(b) != expected {
+		t.Errorf("stored = %q (expected %q)", b, expected)
+	}
+}
+
+func TestRetrieveReader(t *testing.T) {
+	const expected = "Hello, World\n"
+
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("hello.txt", []byte(expected))
+	c := s.dial(ctx)
+
+	r, err := c.RetrieveReader(ctx, "hello.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.(io.Writer); ok {
+		t.Error("reader is writable")
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != expected {
+		t.Errorf("data = %q (expected %q)", b, expected)
+	}
+}
+
+func TestStoreWriter(t *testing.T) {
+	const expected = "Hello, World\n"
+
+	ctx := context.Background()
+	s := newTestServer(t)
+	c := s.dial(ctx)
+
+	w, err := c.StoreWriter(ctx, "hello.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := w.(io.Reader); ok {
+		t.Error("writer is readable")
+	}
+	if _, err := io.WriteString(w, expected); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := s.file("hello.txt"); string(b) != expected {
+		t.Errorf("stored = %q (expected %q)", b, expected)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	const name = "hello world.txt"
+
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile(name, nil)
+	c := s.dial(ctx)
+
+	if err := c.Delete(ctx, name); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.file(name); ok {
+		t.Errorf("%q not deleted", name)
+	}
+	if err := c.Delete(ctx, name); err == nil {
+		t.Error("expected error deleting missing file")
+	}
+}
+
+func TestRename(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("a.txt", nil)
+	c := s.dial(ctx)
+
+	if err := c.Rename(ctx, "a.txt", "b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.file("b.txt"); !ok {
+		t.Error("b.txt does not exist after rename")
+	}
+
+	err := c.Rename(ctx, "a.txt", "c.txt")
+	if reply, ok := err.(Reply); !ok || reply.Code != CodeFileUnavailable {
+		t.Errorf("err = %v (expected %v reply)", err, CodeFileUnavailable)
+	}
+	if _, ok := s.file("c.txt"); ok {
+		t.Error("RNTO sent after failed RNFR")
+	}
+}
+
+func TestSize(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("hello.txt", []byte("Hello, World\n"))
+	c := s.dial(ctx)
+
+	size, err := c.Size(ctx, "hello.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 13 {
+		t.Errorf("size = %d (expected %d)", size, 13)
+	}
+
+	_, err = c.Size(ctx, "missing.txt")
+	if reply, ok := err.(Reply); !ok || reply.Code != CodeFileUnavailable {
+		t.Errorf("err = %v (expected %v reply)", err, CodeFileUnavailable)
+	}
+
+	s.handle("SIZE", func(sc *serverConn, arg string) {
+		sc.reply(502, "Command not implemented")
+	})
+	if _, err := c.Size(ctx, "hello.txt"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("err = %v (expected %v)", err, ErrUnsupported)
+	}
+}
+
+func TestModTime(t *testing.T) {
+	expected := time.Date(2020, 1, 2, 3, 4, 5, 500e6, time.UTC)
+
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("hello.txt", nil)
+	c := s.dial(ctx)
+
+	mtime, err := c.ModTime(ctx, "hello.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !mtime.Equal(expected) {
+		t.Errorf("mtime = %v (expected %v)", mtime, expected)
+	}
+}
+
+func TestSetModTime(t *testing.T) {
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.FixedZone("CET", 3600))
+
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("hello world.txt", nil)
+	var arg string
+	s.handle("MFMT", func(sc *serverConn, a string) {
+		arg = a
+		sc.reply(213, "Modify=%s", a)
+	})
+	c := s.dial(ctx)
+
+	if err := c.SetModTime(ctx, "hello world.txt", mtime); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "20200102020405 hello world.txt"; arg != expected {
+		t.Errorf("arg = %q (expected %q)", arg, expected)
+	}
+
+	s.setFeatures()
+	c = s.dial(ctx) // features are cached per connection
+	if err := c.SetModTime(ctx, "hello world.txt", mtime); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("err = %v (expected %v)", err, ErrUnsupported)
+	}
+}
+
+func TestRetrieveFileFrom(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("hello.txt", []byte("Hello, World\n"))
+	c := s.dial(ctx)
+
+	var buf bytes.Buffer
+	n, err := c.RetrieveFileFrom(ctx, "hello.txt", 7, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 6 || buf.String() != "World\n" {
+		t.Errorf("data = %q, n = %d (expected %q)", buf.String(), n, "World\n")
+	}
+
+	s.handle("REST", func(sc *serverConn, arg string) {
+		sc.reply(502, "Command not implemented")
+	})
+	if _, err := c.RetrieveFileFrom(ctx, "hello.txt", 7, &buf); !errors.Is(err, ErrRestartRejected) {
+		t.Errorf("err = %v (expected %v)", err, ErrRestartRejected)
+	}
+}
+
+func TestStoreFileFrom(t *testing.T) {
+	const expected = "Hello, World\n"
+
+	ctx := context.Background()
+	s := newTestServer(t)
+	c := s.dial(ctx)
+
+	s.setFile("hello.txt", []byte(expected[:7]))
+	n, err := c.StoreFileFrom(ctx, "hello.txt", 7, strings.NewReader(expected))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 6 {
+		t.Errorf("n = %d (expected %d)", n, 6)
+	}
+	if b, _ := s.file("hello.txt"); string(b) != expected {
+		t.Errorf("stored = %q (expected %q)", b, expected)
+	}
+
+	// Fall back to APPE without REST, using a reader that can't seek.
+	s.handle("REST", func(sc *serverConn, arg string) {
+		sc.reply(502, "Command not implemented")
+	})
+	s.setFile("hello.txt", []byte(expected[:7]))
+	if _, err := c.StoreFileFrom(ctx, "hello.txt", 7, bytes.NewBufferString(expected)); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := s.file("hello.txt"); string(b) != expected {
+		t.Errorf("stored = %q (expected %q)", b, expected)
+	}
+}
+
+func TestAppend(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	c := s.dial(ctx)
+
+	for _, line := range []string{"first\n", "second\n"} {
+		if _, err := c.Append(ctx, "log.txt", strings.NewReader(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if b, _ := s.file("log.txt"); string(b) != "first\nsecond\n" {
+		t.Errorf("stored = %q (expected %q)", b, "first\nsecond\n")
+	}
+}
+
+func TestAbortOnCancel(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("small.bin", []byte("Hello, World\n"))
+	s.setFile("large.bin", make([]byte, 16<<20))
+	c := s.dial(ctx)
+
+	for _, name := range []string{"small.bin", "large.bin"} {
+		ctx, cancel := context.WithCancel(ctx)
+		_, conn, err := c.Binary(ctx, "RETR "+name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cancel()
+		if err := conn.Close(); err != nil {
+			t.Errorf("%s: Close: %v", name, err)
+		}
+		reply, err := c.Do(context.Background(), "NOOP")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reply.Code != CodeOkay {
+			t.Errorf("%s: NOOP reply = %v (expected %v)", name, reply, CodeOkay)
+		}
+	}
+}
+
+func TestAbortOnCancelTimeout(t *testing.T) {
+	defer func(d time.Duration) { abortTimeout = d }(abortTimeout)
+	abortTimeout = 50 * time.Millisecond
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("large.bin", make([]byte, 16<<20))
+	s.handle("ABOR", func(sc *serverConn, arg string) {})
+	c := s.dial(ctx)
+
+	ctx, cancel := context.WithCancel(ctx)
+	_, conn, err := c.Binary(ctx, "RETR large.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if err := conn.Close(); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Close: err = %v (expected deadline exceeded)", err)
+	}
+	if _, err := c.Do(context.Background(), "NOOP"); err == nil {
+		t.Error("NOOP succeeded on a closed connection")
+	}
+}
+
+func TestAbort(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("large.bin", make([]byte, 16<<20))
+	c := s.dial(ctx)
+
+	_, conn, err := c.Binary(ctx, "RETR large.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Abort(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Close(); err != nil {
+		t.Errorf("Close after Abort: %v", err)
+	}
+	if err := c.Abort(ctx); err != nil {
+		t.Errorf("Abort without transfer: %v", err)
+	}
+	if reply, err := c.Do(ctx, "NOOP"); err != nil || reply.Code != CodeOkay {
+		t.Errorf("NOOP reply = %v, %v (expected %v)", reply, err, CodeOkay)
+	}
+}
+
+// failCloser fails to close without closing the underlying connection,
+// like a compressor that fails to flush.
+type failCloser struct {
+	io.ReadWriteCloser
+	err error
+}
+
+func (f failCloser) Close() error {
+	return f.err
+}
+
+func TestCloseError(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	c := s.dial(ctx)
+
+	_, conn, err := c.Binary(ctx, "STOR a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closeErr := errors.New("flush failed")
+	tc := conn.(*transferConn)
+	tc.rwc = failCloser{tc.rwc, closeErr}
+	if err := conn.Close(); err != closeErr {
+		t.Errorf("Close: err = %v (expected %v)", err, closeErr)
+	}
+	checkUsable(ctx, t, c)
+}
+
+func TestAbortEncoding(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	c := s.dial(ctx)
+	c.Encoding = latin1{}
+
+	if err := c.Abort(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.count("ABOR"); n != 1 {
+		t.Errorf("ABOR sent %d times (expected 1)", n)
+	}
+}
+
+func TestChmod(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	var arg string
+	s.handle("SITE", func(sc *serverConn, a string) {
+		arg = a
+		sc.reply(200, "Okay")
+	})
+	c := s.dial(ctx)
+
+	if err := c.Chmod(ctx, "hello.txt", 0644|os.ModeDir); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "CHMOD 644 hello.txt"; arg != expected {
+		t.Errorf("SITE %s (expected SITE %s)", arg, expected)
+	}
+
+	s.handle("SITE", func(sc *serverConn, a string) {
+		sc.reply(550, "Permission denied")
+	})
+	err := c.Chmod(ctx, "hello.txt", 0600)
+	if reply, ok := err.(Reply); !ok || reply.Code != CodeFileUnavailable {
+		t.Errorf("err = %v (expected 550 reply)", err)
+	}
+}
+
+func TestFileExists(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("hello.txt", nil)
+
+	for _, features := range [][]string{{"MLST type*;size*;"}, {"SIZE"}} {
+		s.setFeatures(features...)
+		c := s.dial(ctx) // features are cached per connection
+
+		if ok, err := c.FileExists(ctx, "hello.txt"); err != nil || !ok {
+			t.Errorf("%v: FileExists(hello.txt) = %t, %v (expected true)", features, ok, err)
+		}
+		if ok, err := c.FileExists(ctx, "missing.txt"); err != nil || ok {
+			t.Errorf("%v: FileExists(missing.txt) = %t, %v (expected false)", features, ok, err)
+		}
+	}
+
+	s.handle("MLST", func(sc *serverConn, arg string) {
+		sc.reply(550, "Permission denied")
+	})
+	s.setFeatures("MLST type*;size*;")
+	c := s.dial(ctx)
+	if _, err := c.FileExists(ctx, "secret.txt"); err == nil {
+		t.Error("expected error for permission denied")
+	}
+}
+
+func TestTypeTracked(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("hello.txt", []byte("Hello, World\n"))
+	c := s.dial(ctx)
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.RetrieveFile(ctx, "hello.txt", io.Discard); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := c.NameList(ctx, ""); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.count("TYPE"); n != 2 {
+		t.Errorf("TYPE sent %d times (expected 2)", n)
+	}
+}
+
+func TestVerifySize(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("hello.txt", []byte("Hello, World\n"))
+	c := s.dial(ctx)
+	c.VerifySize = true
+
+	if n, err := c.RetrieveFileFrom(ctx, "hello.txt", 7, io.Discard); err != nil || n != 6 {
+		t.Errorf("RetrieveFileFrom = %d, %v (expected 6, nil)", n, err)
+	}
+
+	// Send a truncated file, but report success.
+	s.handle("RETR", func(sc *serverConn, arg string) {
+		sc.transfer(func(conn io.ReadWriter) error {
+			_, err := io.WriteString(conn, "Hello")
+			return err
+		})
+	})
+	if _, err := c.RetrieveFile(ctx, "hello.txt", io.Discard); !errors.Is(err, ErrSizeMismatch) {
+		t.Errorf("err = %v (expected %v)", err, ErrSizeMismatch)
+	}
+
+	s.handle("SIZE", func(sc *serverConn, arg string) {
+		sc.reply(502, "Command not implemented")
+	})
+	if _, err := c.RetrieveFile(ctx, "hello.txt", io.Discard); err != nil {
+		t.Errorf("err = %v (expected check to be skipped)", err)
+	}
+}
+
+func TestStoreFileAllocate(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	var allo []string
+	code := 202
+	s.handle("ALLO", func(sc *serverConn, arg string) {
+		allo = append(allo, arg)
+		sc.reply(code, "ALLO reply")
+	})
+	c := s.dial(ctx)
+
+	// ALLO is only sent if enabled.
+	if _, err := c.StoreFile(ctx, "x.txt", strings.NewReader("Hello")); err != nil {
+		t.Fatal(err)
+	}
+	c.AllocateOnStore = true
+	r := strings.NewReader("Hello, World\n")
+	r.Seek(7, io.SeekStart)
+	if _, err := c.StoreFile(ctx, "a.txt", r); err != nil {
+		t.Fatal(err)
+	}
+	code = 502
+	if _, err := c.StoreFile(ctx, "b.txt", strings.NewReader("Hello")); err != nil {
+		t.Fatal(err)
+	}
+	code = 452
+	if _, err := c.StoreFile(ctx, "d.txt", strings.NewReader("Hi")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.StoreFile(ctx, "c.txt", struct{ io.Reader }{strings.NewReader("Hello")}); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"6", "5", "2"}; !reflect.DeepEqual(allo, expected) {
+		t.Errorf("ALLO %q (expected %q)", allo, expected)
+	}
+	if b, _ := s.file("a.txt"); string(b) != "World\n" {
+		t.Errorf("stored %q", b)
+	}
+	if b, _ := s.file("b.txt"); string(b) != "Hello" {
+		t.Errorf("stored %q", b)
+	}
+}
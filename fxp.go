@@ -0,0 +1,58 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"context"
+)
+
+// FXP copies the file at srcPath on the server of src to dstPath on the
+// server of dst in image mode, without passing the data through the client.
+// The destination server is put in passive mode and the source server is
+// told to connect to it using PORT or EPRT, so the source server must be
+// able to reach the address of the destination server as seen by the
+// client. Many servers refuse such transfers by default, because PORT
+// then names a host other than the client.
+func FXP(ctx context.Context, src *Client, srcPath string, dst *Client, dstPath string) error {
+	if err := src.setType(ctx, "I"); err != nil {
+		return err
+	}
+	if err := dst.setType(ctx, "I"); err != nil {
+		return err
+	}
+	addr, err := dst.passiveAddr(ctx)
+	if err != nil {
+		return err
+	}
+	if err := src.sendPort(ctx, addr); err != nil {
+		return err
+	}
+
+	// The destination waits for the connection of the source.
+	if _, err := dst.startTransfer(ctx, "STOR "+dstPath, 0); err != nil {
+		return err
+	}
+	if _, err := src.startTransfer(ctx, "RETR "+srcPath, 0); err != nil {
+		dst.mu.Lock()
+		dst.abort(true)
+		dst.mu.Unlock()
+		return err
+	}
+
+	err = completeFXP(ctx, src)
+	if derr := completeFXP(ctx, dst); err == nil {
+		err = derr
+	}
+	return err
+}
+
+// completeFXP reads the completion reply of a transfer started by FXP.
+func completeFXP(ctx context.Context, c *Client) error {
+	reply, err := c.ReadReply(ctx)
+	if err != nil {
+		return err
+	} else if !reply.PositiveComplete() {
+		return reply
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFXP(t *testing.T) {
+	const expected = "Hello, World\n"
+
+	ctx := context.Background()
+	s1 := newTestServer(t)
+	s1.setFile("hello.txt", []byte(expected))
+	s2 := newTestServer(t)
+	src, dst := s1.dial(ctx), s2.dial(ctx)
+
+	if err := FXP(ctx, src, "hello.txt", dst, "copy.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := s2.file("copy.txt"); string(b) != expected {
+		t.Errorf("copied %q (expected %q)", b, expected)
+	}
+
+	s2.handle("STOR", func(sc *serverConn, arg string) {
+		sc.reply(553, "Permission denied")
+	})
+	if err := FXP(ctx, src, "hello.txt", dst, "denied.txt"); !IsPermanent(err) {
+		t.Errorf("err = %v (expected 553 reply)", err)
+	}
+	if n := s1.count("RETR"); n != 1 {
+		t.Errorf("RETR sent %d times (expected 1)", n)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Glob returns the names of all remote files matching pattern, using the
+// syntax of path.Match. Directories are listed using NLST as needed, so
+// patterns can span multiple path elements, like logs/2023-*/access.log.
+// If a directory in the pattern doesn't exist, it simply matches nothing.
+// The only possible returned error besides I/O and protocol errors is
+// path.ErrBadPattern.
+func (c *Client) Glob(ctx context.Context, pattern string) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	dirs := []string{""}
+	if strings.HasPrefix(pattern, "/") {
+		dirs = []string{"/"}
+	}
+	elems := strings.Split(strings.Trim(pattern, "/"), "/")
+	for i, elem := range elems {
+		if i < len(elems)-1 && !hasMeta(elem) {
+			for j := range dirs {
+				dirs[j] = path.Join(dirs[j], elem)
+			}
+			continue
+		}
+		var matches []string
+		for _, dir := range dirs {
+			m, err := c.glob(ctx, dir, elem)
+			if err != nil {
+				return nil, err
+			}
+			matches = append(matches, m...)
+		}
+		dirs = matches
+	}
+	return dirs, nil
+}
+
+// glob returns the names in dir matching pattern.
+// A directory that can't be listed matches nothing. So does a file:
+// many servers, like vsftpd and ProFTPD, list a file using NLST by
+// returning its own name, so a listing of dir consisting of only dir
+// itself is taken to be a file.
+func (c *Client) glob(ctx context.Context, dir, pattern string) ([]string, error) {
+	names, err := c.NameList(ctx, dir)
+	if reply, ok := err.(Reply); ok && (reply.Code == CodeFileUnavailable || reply.Code == CodeActionNotTaken) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if len(names) == 1 && dir != "" && dir != "/" && path.Clean(names[0]) == path.Clean(dir) {
+		return nil, nil
+	}
+	var matches []string
+	for _, name := range names {
+		name = path.Base(name)
+		if matched, _ := path.Match(pattern, name); matched {
+			matches = append(matches, path.Join(dir, name))
+		}
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// hasMeta reports whether pattern contains any of the magic characters
+// recognized by path.Match.
+func hasMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, `*?[\`)
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGlob(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	for _, name := range []string{
+		"logs/2023-01/access.log",
+		"logs/2023-01/error.log",
+		"logs/2023-02/access.log",
+		"logs/2024-01/access.log",
+		"readme.txt",
+	} {
+		s.setFile(name, nil)
+	}
+	c := s.dial(ctx)
+
+	tests := []struct {
+		Pattern string
+		Matches []string
+	}{
+		{"logs/2023-*/access.log", []string{"logs/2023-01/access.log", "logs/2023-02/access.log"}},
+		{"logs/*/*.log", []string{"logs/2023-01/access.log", "logs/2023-01/error.log", "logs/2023-02/access.log", "logs/2024-01/access.log"}},
+		{"*.txt", []string{"readme.txt"}},
+		{"/logs/2024-01/*", []string{"/logs/2024-01/access.log"}},
+		{"readme.txt", []string{"readme.txt"}},
+		{"missing/*.log", nil},
+		{"logs/2025-*/access.log", nil},
+	}
+	for i, tt := range tests {
+		matches, err := c.Glob(ctx, tt.Pattern)
+		if err != nil {
+			t.Errorf("tests[%d] error: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(matches, tt.Matches) {
+			t.Errorf("tests[%d]: expected %q (got %q)", i, tt.Matches, matches)
+		}
+	}
+
+	if _, err := c.Glob(ctx, "[a-"); err == nil {
+		t.Error("expected error for bad pattern")
+	}
+}
+
+func TestGlobFiles(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	for _, name := range []string{"d/a.txt", "f.txt"} {
+		s.setFile(name, nil)
+	}
+	// List a file by its own name, like vsftpd and ProFTPD.
+	s.handle("NLST", func(sc *serverConn, arg string) {
+		var names []string
+		if _, ok := sc.s.file(arg); ok {
+			names = []string{arg}
+		} else {
+			lines, _ := sc.s.list(arg)
+			for _, line := range lines[2:] {
+				names = append(names, line[strings.Index(line, "; ")+2:])
+			}
+		}
+		sc.transfer(func(conn io.ReadWriter) error {
+			for _, name := range names {
+				if _, err := io.WriteString(conn, name+"\r\n"); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+	c := s.dial(ctx)
+
+	matches, err := c.Glob(ctx, "*/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"d/a.txt"}; !reflect.DeepEqual(matches, expected) {
+		t.Errorf("matches = %q (expected %q)", matches, expected)
+	}
+}
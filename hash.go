@@ -0,0 +1,78 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// Hash algorithms that can be passed to Hash.
+const (
+	HashCRC32  = "CRC32"
+	HashMD5    = "MD5"
+	HashSHA1   = "SHA-1"
+	HashSHA256 = "SHA-256"
+	HashSHA512 = "SHA-512"
+)
+
+// hashCommands are the non-standard commands that compute a hash,
+// by algorithm.
+var hashCommands = map[string]string{
+	HashCRC32:  "XCRC",
+	HashMD5:    "XMD5",
+	HashSHA1:   "XSHA1",
+	HashSHA256: "XSHA256",
+	HashSHA512: "XSHA512",
+}
+
+// Hash returns the hash of the file at path computed by the server, so a
+// transfer can be verified without transferring the file again. If the
+// server advertises the HASH command of draft-bryan-ftpext-hash, the
+// algorithm is selected using OPTS HASH. Otherwise the non-standard command
+// for the algorithm, like XMD5 or XCRC, is used if advertised. If the
+// server supports neither, an error wrapping ErrUnsupported is returned.
+func (c *Client) Hash(ctx context.Context, path string, algo string) ([]byte, error) {
+	algo = strings.ToUpper(algo)
+	features, err := c.Features(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if features.Supports("HASH") {
+		if err := c.simpleCommand(ctx, "OPTS HASH "+algo); err != nil {
+			return nil, err
+		}
+		reply, err := c.sendCommand(ctx, "HASH "+path)
+		if err != nil {
+			return nil, err
+		} else if reply.Code != CodeFileStatus {
+			return nil, reply
+		}
+		// 213 <algorithm> <start>-<end> <hash> <pathname>
+		fields := strings.Fields(reply.Msg)
+		if len(fields) < 3 {
+			return nil, errors.New("ftp: HASH reply provided no hash")
+		}
+		return hex.DecodeString(fields[2])
+	}
+
+	command, ok := hashCommands[algo]
+	if !ok || !features.Supports(command) {
+		return nil, unsupported("hash " + algo)
+	}
+	reply, err := c.sendCommand(ctx, command+" "+path)
+	if err != nil {
+		return nil, err
+	} else if !reply.PositiveComplete() {
+		return nil, reply
+	}
+	for _, field := range strings.Fields(reply.Msg) {
+		if sum, err := hex.DecodeString(field); err == nil {
+			return sum, nil
+		}
+	}
+	return nil, errors.New("ftp: " + command + " reply provided no hash")
+}
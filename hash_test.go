@@ -0,0 +1,55 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	var algo string
+	s.handle("OPTS", func(sc *serverConn, arg string) {
+		algo = arg
+		sc.reply(200, "Okay")
+	})
+	s.handle("HASH", func(sc *serverConn, arg string) {
+		sc.reply(213, "SHA-256 0-5 0A0B0C %s", arg)
+	})
+	s.handle("XCRC", func(sc *serverConn, arg string) {
+		sc.reply(250, "DEADBEEF")
+	})
+
+	tests := []struct {
+		Features []string
+		Algo     string
+		Expected []byte
+	}{
+		{[]string{"HASH SHA-1;SHA-256*"}, "sha-256", []byte{0x0a, 0x0b, 0x0c}},
+		{[]string{"XCRC"}, HashCRC32, []byte{0xde, 0xad, 0xbe, 0xef}},
+	}
+	for i, tt := range tests {
+		s.setFeatures(tt.Features...)
+		c := s.dial(ctx) // features are cached per connection
+		sum, err := c.Hash(ctx, "hello.txt", tt.Algo)
+		if err != nil {
+			t.Errorf("tests[%d] error: %v", i, err)
+			continue
+		}
+		if !bytes.Equal(sum, tt.Expected) {
+			t.Errorf("tests[%d]: expected %#v (got %#v)", i, tt.Expected, sum)
+		}
+	}
+	if algo != "HASH SHA-256" {
+		t.Errorf("OPTS %s (expected OPTS HASH SHA-256)", algo)
+	}
+
+	c := s.dial(ctx)
+	if _, err := c.Hash(ctx, "hello.txt", HashMD5); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("err = %v (expected %v)", err, ErrUnsupported)
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"context"
+	"time"
+)
+
+// NoOp sends the NOOP command, which has no effect other than
+// keeping the control connection alive.
+func (c *Client) NoOp(ctx context.Context) error {
+	return c.simpleCommand(ctx, "NOOP")
+}
+
+// KeepAlive sends NOOP every interval to prevent firewalls and servers from
+// closing an idle control connection. It blocks until ctx is done or NOOP
+// fails, so it is meant to run in its own goroutine.
+//
+// KeepAlive is the only method that may be called concurrently with other
+// methods of the Client. It only sends NOOP if the control connection has
+// been idle for at least interval and no transfer is in progress, so it
+// never interleaves with a transfer or a sequence of related commands.
+func (c *Client) KeepAlive(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := c.ping(interval); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// ping sends NOOP if the control connection has been idle for interval.
+func (c *Client) ping(interval time.Duration) error {
+	if !c.mu.TryLock() {
+		return nil // command in progress
+	}
+	defer c.mu.Unlock()
+	if c.transferring != nil || c.opening || time.Since(c.lastUsed) < interval {
+		return nil
+	}
+	if err := c.writeLine("NOOP"); err != nil {
+		return err
+	}
+	reply, err := c.readResponse()
+	if err != nil {
+		return err
+	} else if !reply.PositiveComplete() {
+		return reply
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestNoOp(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	c := s.dial(ctx)
+
+	if err := c.NoOp(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.count("NOOP"); n != 1 {
+		t.Errorf("NOOP sent %d times (expected 1)", n)
+	}
+}
+
+func TestKeepAlive(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	c := s.dial(ctx)
+
+	kctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	if err := c.KeepAlive(kctx, 10*time.Millisecond); err != context.DeadlineExceeded {
+		t.Fatalf("KeepAlive = %v (expected %v)", err, context.DeadlineExceeded)
+	}
+	if n := s.count("NOOP"); n == 0 {
+		t.Errorf("NOOP not sent")
+	}
+}
+
+func TestKeepAliveDuringTransfer(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("a.txt", []byte("hello"))
+	c := s.dial(ctx)
+
+	_, conn, err := c.Binary(ctx, "RETR a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	c.KeepAlive(kctx, 10*time.Millisecond)
+	if n := s.count("NOOP"); n != 0 {
+		t.Errorf("NOOP sent %d times during transfer (expected 0)", n)
+	}
+	if _, err := io.ReadAll(conn); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKeepAliveDuringTransferSetup(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	c := s.dial(ctx)
+
+	// A tick between the commands setting up a transfer, when the
+	// mutex is released, must not interleave NOOP with them.
+	c.mu.Lock()
+	c.opening = true
+	c.mu.Unlock()
+	if err := c.ping(0); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.count("NOOP"); n != 0 {
+		t.Errorf("NOOP sent %d times during transfer setup (expected 0)", n)
+	}
+
+	c.mu.Lock()
+	c.opening = false
+	c.mu.Unlock()
+	if err := c.ping(0); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.count("NOOP"); n != 1 {
+		t.Errorf("NOOP sent %d times after transfer setup (expected 1)", n)
+	}
+}
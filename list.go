@@ -0,0 +1,403 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NameList returns the names of the files in the directory at path using NLST.
+// An empty path lists the current directory.
+func (c *Client) NameList(ctx context.Context, path string) ([]string, error) {
+	return c.textLines(ctx, withPath("NLST", path))
+}
+
+// withPath appends path as argument to command if it is not empty.
+func withPath(command, path string) string {
+	if path == "" {
+		return command
+	}
+	return command + " " + path
+}
+
+// textLines sends a command, reads the ASCII data connection to the end
+// and returns its non-empty lines.
+func (c *Client) textLines(ctx context.Context, command string) ([]string, error) {
+	var lines []string
+	err := c.RetrLines(ctx, command, func(line string) error {
+		if line != "" {
+			lines = append(lines, line)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// RetrLines sends a command that sends text over an ASCII data connection,
+// like LIST or NLST, and calls fn for each line without its line ending.
+// The lines are read as they arrive, so large listings aren't loaded into
+// memory at once. If fn returns an error, the transfer is aborted and the
+// error is returned. Otherwise the completion reply is checked.
+func (c *Client) RetrLines(ctx context.Context, command string, fn func(line string) error) error {
+	_, conn, err := c.Text(ctx, command)
+	if err != nil {
+		return err
+	}
+	return c.retrLines(ctx, conn, fn)
+}
+
+// retrLines reads the lines of a data connection like RetrLines.
+func (c *Client) retrLines(ctx context.Context, conn io.ReadCloser, fn func(line string) error) error {
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err == io.EOF && line == "" {
+			break
+		} else if err != nil && err != io.EOF {
+			conn.Close()
+			return err
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		if line, err = c.decode(line); err == nil {
+			err = fn(line)
+		}
+		if err != nil {
+			if aerr := c.Abort(ctx); aerr != nil {
+				return aerr
+			}
+			return err
+		}
+	}
+	return conn.Close()
+}
+
+// StorLines sends a command that receives text over an ASCII data
+// connection, like STOR, and writes the lines read from r terminated by
+// CRLF, whatever their line ending in r. The completion reply is checked
+// when the data connection is closed.
+func (c *Client) StorLines(ctx context.Context, command string, r io.Reader) error {
+	_, conn, err := c.Text(ctx, command)
+	if err != nil {
+		return err
+	}
+	br := bufio.NewReader(r)
+	w := bufio.NewWriter(conn)
+	for {
+		line, err := br.ReadString('\n')
+		if err == io.EOF && line == "" {
+			break
+		} else if err != nil && err != io.EOF {
+			conn.Close()
+			return err
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		if line, err = c.encode(line); err == nil {
+			_, err = w.WriteString(line + "\r\n")
+		}
+		if err != nil {
+			conn.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		conn.Close()
+		return err
+	}
+	return conn.Close()
+}
+
+// Stat lists the file or directory at path using STAT, which sends the
+// listing over the control connection instead of a data connection.
+// Most servers format the listing like LIST. Lines that can't be parsed
+// are returned as entries with only Raw set.
+func (c *Client) Stat(ctx context.Context, path string) ([]Entry, error) {
+	reply, err := c.sendCommand(ctx, withPath("STAT", path))
+	if err != nil {
+		return nil, err
+	} else if !reply.PositiveComplete() {
+		return nil, reply
+	}
+	lines := reply.Lines()
+	if len(lines) < 3 {
+		return nil, nil
+	}
+	return parseList(lines[1:len(lines)-1], time.Now()), nil
+}
+
+// List lists the directory at path using LIST. An empty path lists the
+// current directory. Both the Unix ls -l format and the MS-DOS format used
+// by IIS are recognized. Lines that can't be parsed are returned as entries
+// with only Raw set. Prefer MLSD if the server supports it, because the
+// LIST format isn't standardized.
+func (c *Client) List(ctx context.Context, path string) ([]Entry, error) {
+	lines, err := c.textLines(ctx, withPath("LIST", path))
+	if err != nil {
+		return nil, err
+	}
+	return parseList(lines, time.Now()), nil
+}
+
+// ListRaw is like List, but also returns the listing exactly as sent by
+// the server, for example to show it to the user, because parsing loses
+// information. The raw listing is kept in memory as a whole, which takes
+// considerable memory for huge directories.
+func (c *Client) ListRaw(ctx context.Context, path string) ([]Entry, []byte, error) {
+	_, conn, err := c.transfer(ctx, withPath("LIST", path), "A", 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	var raw bytes.Buffer
+	tee := struct {
+		io.Reader
+		io.Closer
+	}{io.TeeReader(conn, &raw), conn}
+	var lines []string
+	err = c.retrLines(ctx, tee, func(line string) error {
+		if line != "" {
+			lines = append(lines, line)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return parseList(lines, time.Now()), raw.Bytes(), nil
+}
+
+// ListArgs is like List, but passes args as is to LIST, for example "-la"
+// to include hidden files or "-R" to list recursively on servers that
+// support it. Argument support depends on the server: some servers treat
+// unknown arguments as a path, so the listing may fail or list something
+// else. Lines that aren't entries, like the directory headers of a
+// recursive listing, are returned as entries with only Raw set.
+func (c *Client) ListArgs(ctx context.Context, args string) ([]Entry, error) {
+	lines, err := c.textLines(ctx, withPath("LIST", args))
+	if err != nil {
+		return nil, err
+	}
+	return parseList(lines, time.Now()), nil
+}
+
+// ListEntries lists the directory at path using the best command the
+// server implements: MLSD, or LIST if the server doesn't implement MLSD,
+// or NLST if it doesn't implement LIST either. Entries listed by NLST only
+// have Name set. Use LastListCommand to find out which command was used
+// and thus which metadata to expect.
+func (c *Client) ListEntries(ctx context.Context, path string) ([]Entry, error) {
+	c.listCommand = "MLSD"
+	entries, err := c.MLSD(ctx, path)
+	if !isUnsupportedErr(err) {
+		return entries, err
+	}
+	c.listCommand = "LIST"
+	entries, err = c.List(ctx, path)
+	if !isUnsupportedErr(err) {
+		return entries, err
+	}
+	c.listCommand = "NLST"
+	names, err := c.NameList(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	entries = make([]Entry, len(names))
+	for i, name := range names {
+		entries[i] = Entry{Name: name}
+	}
+	return entries, nil
+}
+
+// isUnsupportedErr reports whether err is a Reply indicating the command
+// is not implemented.
+func isUnsupportedErr(err error) bool {
+	reply, ok := err.(Reply)
+	return ok && isUnsupported(reply)
+}
+
+// LastListCommand returns the command used by the last call of
+// ListEntries: "MLSD", "LIST" or "NLST".
+func (c *Client) LastListCommand() string {
+	return c.listCommand
+}
+
+// parseList parses the lines of a LIST-style listing.
+func parseList(lines []string, now time.Time) []Entry {
+	var entries []Entry
+	for _, line := range lines {
+		// Keep trailing spaces, which may be part of the name.
+		line = strings.TrimLeft(strings.TrimSuffix(line, "\r"), " \t")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "total ") {
+			continue
+		}
+		e, ok := parseListLine(line, now)
+		if !ok {
+			e = Entry{Raw: line}
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// parseListLine parses a line of a LIST-style listing, detecting whether it
+// is in Unix or MS-DOS format.
+func parseListLine(line string, now time.Time) (Entry, bool) {
+	if len(line) > 0 && line[0] >= '0' && line[0] <= '9' {
+		return parseDOSLine(line)
+	}
+	return parseUnixLine(line, now)
+}
+
+var months = map[string]time.Month{
+	"jan": time.January, "feb": time.February, "mar": time.March,
+	"apr": time.April, "may": time.May, "jun": time.June,
+	"jul": time.July, "aug": time.August, "sep": time.September,
+	"oct": time.October, "nov": time.November, "dec": time.December,
+}
+
+// parseUnixLine parses a line in the format of ls -l:
+//
+//	-rw-r--r--   1 owner    group        1024 Jan 02 03:04 name
+//	drwxr-xr-x   2 owner    group        4096 Jan 02  2019 name
+//
+// The group may be missing. Dates without year are assumed to be within
+// the year before now. Times are in UTC, because the server's time zone
+// is unknown.
+func parseUnixLine(line string, now time.Time) (Entry, bool) {
+	if len(line) < 10 || !strings.ContainsRune("-dlbcps", rune(line[0])) {
+		return Entry{}, false
+	}
+	fields, offsets := splitFields(line)
+	// Find the month, preceded by at least mode, links, owner and size.
+	m := -1
+	for i := 4; i+3 < len(fields); i++ {
+		if _, ok := months[strings.ToLower(fields[i])]; ok {
+			m = i
+			break
+		}
+	}
+	if m == -1 {
+		return Entry{}, false
+	}
+	size, err := strconv.ParseInt(fields[m-1], 10, 64)
+	if err != nil {
+		return Entry{}, false
+	}
+	mtime, ok := parseUnixTime(fields[m], fields[m+1], fields[m+2], now)
+	if !ok {
+		return Entry{}, false
+	}
+
+	e := Entry{
+		Name:    line[offsets[m+3]:],
+		Size:    size,
+		ModTime: mtime,
+		Raw:     line,
+	}
+	switch line[0] {
+	case 'l':
+		e.Symlink = true
+		if name, target, ok := strings.Cut(e.Name, " -> "); ok {
+			e.Name, e.Target = name, target
+		}
+	case '-':
+		e.Type = EntryFile
+	case 'd':
+		e.Type = EntryDir
+		switch e.Name {
+		case ".":
+			e.Type = EntryCurrentDir
+		case "..":
+			e.Type = EntryParentDir
+		}
+	}
+	return e, true
+}
+
+// parseDOSLine parses a line in the MS-DOS format used by IIS:
+//
+//	01-02-20  03:04PM                 1024 name
+//	01-02-2020  03:04PM       <DIR>          name
+//
+// Times are in UTC, because the server's time zone is unknown.
+func parseDOSLine(line string) (Entry, bool) {
+	fields, offsets := splitFields(line)
+	if len(fields) < 4 {
+		return Entry{}, false
+	}
+	layout := "01-02-06 03:04PM"
+	if len(fields[0]) == len("01-02-2006") {
+		layout = "01-02-2006 03:04PM"
+	}
+	mtime, err := time.ParseInLocation(layout, fields[0]+" "+strings.ToUpper(fields[1]), time.UTC)
+	if err != nil {
+		return Entry{}, false
+	}
+	e := Entry{
+		Name:    line[offsets[3]:],
+		ModTime: mtime,
+		Raw:     line,
+	}
+	if strings.EqualFold(fields[2], "<DIR>") {
+		e.Type = EntryDir
+	} else {
+		e.Type = EntryFile
+		e.Size, err = strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return Entry{}, false
+		}
+	}
+	return e, true
+}
+
+// parseUnixTime parses the date of an ls -l line.
+func parseUnixTime(month, day, timeOrYear string, now time.Time) (time.Time, bool) {
+	mon := months[strings.ToLower(month)]
+	d, err := strconv.Atoi(day)
+	if err != nil || d < 1 || d > 31 {
+		return time.Time{}, false
+	}
+	if i := strings.IndexByte(timeOrYear, ':'); i >= 0 {
+		hour, err1 := strconv.Atoi(timeOrYear[:i])
+		min, err2 := strconv.Atoi(timeOrYear[i+1:])
+		if err1 != nil || err2 != nil {
+			return time.Time{}, false
+		}
+		now = now.UTC()
+		t := time.Date(now.Year(), mon, d, hour, min, 0, 0, time.UTC)
+		if t.After(now.AddDate(0, 0, 1)) {
+			t = t.AddDate(-1, 0, 0)
+		}
+		return t, true
+	}
+	year, err := strconv.Atoi(timeOrYear)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Date(year, mon, d, 0, 0, 0, 0, time.UTC), true
+}
+
+// splitFields splits s around runs of spaces like strings.Fields,
+// and also returns the offset of each field in s.
+func splitFields(s string) (fields []string, offsets []int) {
+	start := -1
+	for i := 0; i <= len(s); i++ {
+		if i == len(s) || s[i] == ' ' || s[i] == '\t' {
+			if start >= 0 {
+				fields = append(fields, s[start:i])
+				offsets = append(offsets, start)
+				start = -1
+			}
+		} else if start < 0 {
+			start = i
+		}
+	}
+	return fields, offsets
+}
@@ -0,0 +1,313 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNameList(t *testing.T) {
+	expected := []string{"a.txt", "b.txt"}
+
+	ctx := context.Background()
+	s := newTestServer(t)
+	for _, name := range expected {
+		s.setFile(name, nil)
+	}
+	c := s.dial(ctx)
+
+	names, err := c.NameList(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("names = %q (expected %q)", names, expected)
+	}
+}
+
+func TestParseUnixLine(t *testing.T) {
+	now := time.Date(2020, 6, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		Input string
+		Entry Entry
+	}{
+		{
+			"-rw-r--r--   1 owner    group        1024 Jan 02 03:04 hello world.txt",
+			Entry{Name: "hello world.txt", Size: 1024, ModTime: time.Date(2020, 1, 2, 3, 4, 0, 0, time.UTC), Type: EntryFile},
+		},
+		{
+			"drwxr-xr-x   2 owner    group        4096 Dec 24  2019 pub",
+			Entry{Name: "pub", Size: 4096, ModTime: time.Date(2019, 12, 24, 0, 0, 0, 0, time.UTC), Type: EntryDir},
+		},
+		{
+			"-rw-r--r--   1 owner  512 Dec 31 23:59 nogroup",
+			Entry{Name: "nogroup", Size: 512, ModTime: time.Date(2019, 12, 31, 23, 59, 0, 0, time.UTC), Type: EntryFile},
+		},
+		{
+			"drwxr-xr-x   2 owner    group        4096 Jun 15 11:00 ..",
+			Entry{Name: "..", Size: 4096, ModTime: time.Date(2020, 6, 15, 11, 0, 0, 0, time.UTC), Type: EntryParentDir},
+		},
+		{
+			"lrwxrwxrwx   1 owner    group          11 Jun 15 11:00 latest -> pub/v1.2.3",
+			Entry{Name: "latest", Size: 11, ModTime: time.Date(2020, 6, 15, 11, 0, 0, 0, time.UTC), Symlink: true, Target: "pub/v1.2.3"},
+		},
+	}
+	for i, tt := range tests {
+		tt.Entry.Raw = tt.Input
+		e, ok := parseUnixLine(tt.Input, now)
+		if !ok {
+			t.Errorf("tests[%d]: failed to parse", i)
+			continue
+		}
+		if !reflect.DeepEqual(tt.Entry, e) {
+			t.Errorf("tests[%d]: expected %#v (got %#v)", i, tt.Entry, e)
+		}
+	}
+
+	for _, line := range []string{"total 12", "-rw-r--r-- 1 owner group size Jan 02 03:04 x", "garbage"} {
+		if _, ok := parseUnixLine(line, now); ok {
+			t.Errorf("parseUnixLine(%q): expected failure", line)
+		}
+	}
+}
+
+func TestStat(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.handle("STAT", func(sc *serverConn, arg string) {
+		sc.proto.PrintfLine("213-Status of %s:", arg)
+		sc.proto.PrintfLine("total 8")
+		sc.proto.PrintfLine("-rw-r--r--   1 owner    group          13 Jan 02  2019 hello.txt")
+		sc.proto.PrintfLine(" something unexpected")
+		sc.reply(213, "End of status")
+	})
+	c := s.dial(ctx)
+
+	entries, err := c.Stat(ctx, "pub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("len(entries) = %d (expected 2)", len(entries))
+	}
+	if entries[0].Name != "hello.txt" || entries[0].Size != 13 {
+		t.Errorf("entries[0] = %#v", entries[0])
+	}
+	if entries[1].Raw != "something unexpected" {
+		t.Errorf("entries[1].Raw = %q", entries[1].Raw)
+	}
+}
+
+func TestParseDOSLine(t *testing.T) {
+	tests := []struct {
+		Input string
+		Entry Entry
+	}{
+		{
+			"01-02-20  03:04PM                 1024 hello world.txt",
+			Entry{Name: "hello world.txt", Size: 1024, ModTime: time.Date(2020, 1, 2, 15, 4, 0, 0, time.UTC), Type: EntryFile},
+		},
+		{
+			"12-24-1999  11:00AM       <DIR>          pub",
+			Entry{Name: "pub", ModTime: time.Date(1999, 12, 24, 11, 0, 0, 0, time.UTC), Type: EntryDir},
+		},
+	}
+	for i, tt := range tests {
+		tt.Entry.Raw = tt.Input
+		e, ok := parseListLine(tt.Input, time.Now())
+		if !ok {
+			t.Errorf("tests[%d]: failed to parse", i)
+			continue
+		}
+		if !reflect.DeepEqual(tt.Entry, e) {
+			t.Errorf("tests[%d]: expected %#v (got %#v)", i, tt.Entry, e)
+		}
+	}
+}
+
+func TestParseList(t *testing.T) {
+	lines := []string{
+		"total 2",
+		"-rw-r--r--   1 owner    group        1024 Jan 02  2019 trailing  \r",
+		"  01-02-20  03:04PM                 1024 dos ",
+		"   ",
+	}
+	entries := parseList(lines, time.Now())
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+	if expected := []string{"trailing  ", "dos "}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("names = %q (expected %q)", names, expected)
+	}
+}
+
+func TestList(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("pub/hello.txt", nil)
+	s.setFile("readme.txt", nil)
+	c := s.dial(ctx)
+
+	entries, err := c.List(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+	if expected := []string{"pub", "readme.txt"}; !reflect.DeepEqual(names, expected) {
+		t.Fatalf("names = %q (expected %q)", names, expected)
+	}
+	if !entries[0].IsDir() || entries[1].IsDir() {
+		t.Errorf("IsDir = %v, %v (expected true, false)", entries[0].IsDir(), entries[1].IsDir())
+	}
+}
+
+func TestRetrLines(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		s.setFile(name, nil)
+	}
+	c := s.dial(ctx)
+
+	var lines []string
+	err := c.RetrLines(ctx, "NLST", func(line string) error {
+		lines = append(lines, line)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"a.txt", "b.txt", "c.txt"}; !reflect.DeepEqual(lines, expected) {
+		t.Errorf("lines = %q (expected %q)", lines, expected)
+	}
+
+	errStop := errors.New("stop")
+	lines = nil
+	err = c.RetrLines(ctx, "NLST", func(line string) error {
+		lines = append(lines, line)
+		return errStop
+	})
+	if err != errStop {
+		t.Errorf("err = %v (expected %v)", err, errStop)
+	}
+	if len(lines) != 1 {
+		t.Errorf("lines = %q (expected one line)", lines)
+	}
+	if err := c.NoOp(ctx); err != nil {
+		t.Error("client unusable after stopping early:", err)
+	}
+}
+
+func TestStorLines(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	c := s.dial(ctx)
+
+	if err := c.StorLines(ctx, "STOR a.txt", strings.NewReader("one\ntwo\r\n\nthree")); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := s.file("a.txt"); string(b) != "one\r\ntwo\r\n\r\nthree\r\n" {
+		t.Errorf("stored = %q", b)
+	}
+}
+
+func TestListArgs(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	var args string
+	s.handle("LIST", func(sc *serverConn, arg string) {
+		args = arg
+		sc.transfer(func(conn io.ReadWriter) error {
+			_, err := io.WriteString(conn, ".:\r\n"+
+				"total 8\r\n"+
+				"-rw-r--r--   1 owner    group          13 Jan 02  2019 .hidden\r\n")
+			return err
+		})
+	})
+	c := s.dial(ctx)
+
+	entries, err := c.ListArgs(ctx, "-laR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args != "-laR" {
+		t.Errorf("LIST %s (expected LIST -laR)", args)
+	}
+	if len(entries) != 2 || entries[0].Raw != ".:" || entries[1].Name != ".hidden" {
+		t.Errorf("entries = %#v", entries)
+	}
+}
+
+func TestListEntries(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("readme.txt", []byte("Hello, World\n"))
+	c := s.dial(ctx)
+
+	unsupported := func(sc *serverConn, arg string) {
+		sc.reply(502, "Command not implemented")
+	}
+	tests := []struct {
+		Disable string
+		Command string
+		Type    EntryType
+	}{
+		{"", "MLSD", EntryFile},
+		{"MLSD", "LIST", EntryFile},
+		{"LIST", "NLST", ""},
+	}
+	for i, tt := range tests {
+		if tt.Disable != "" {
+			s.handle(tt.Disable, unsupported)
+		}
+		entries, err := c.ListEntries(ctx, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.LastListCommand() != tt.Command {
+			t.Errorf("tests[%d]: expected %s (got %s)", i, tt.Command, c.LastListCommand())
+		}
+		j := slices.IndexFunc(entries, func(e Entry) bool { return e.Name == "readme.txt" })
+		if j < 0 || entries[j].Type != tt.Type {
+			t.Errorf("tests[%d]: expected readme.txt of type %q (got %#v)", i, tt.Type, entries)
+		}
+	}
+}
+
+func TestListRaw(t *testing.T) {
+	const listing = "total 8\r\n" +
+		"-rw-r--r--   1 owner    group          13 Jan 02  2019 hello.txt\r\n" +
+		"strange line\r\n"
+
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.handle("LIST", func(sc *serverConn, arg string) {
+		sc.transfer(func(conn io.ReadWriter) error {
+			_, err := io.WriteString(conn, listing)
+			return err
+		})
+	})
+	c := s.dial(ctx)
+
+	entries, raw, err := c.ListRaw(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(raw) != listing {
+		t.Errorf("raw = %q (expected %q)", raw, listing)
+	}
+	if len(entries) != 2 || entries[0].Name != "hello.txt" || entries[1].Raw != "strange line" {
+		t.Errorf("entries = %#v", entries)
+	}
+}
@@ -0,0 +1,31 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import "strings"
+
+// Direction is the direction of a line on the control connection.
+type Direction int
+
+const (
+	Sent     Direction = iota // command sent to the server
+	Received                  // reply line received from the server
+)
+
+func (d Direction) String() string {
+	if d == Sent {
+		return "-->"
+	}
+	return "<--"
+}
+
+// log passes line to the Logger, if any. The password of PASS is masked.
+func (c *Client) log(dir Direction, line string) {
+	if c.Logger == nil {
+		return
+	}
+	if dir == Sent && len(line) > 5 && strings.EqualFold(line[:5], "PASS ") {
+		line = line[:5] + "****"
+	}
+	c.Logger(dir, line)
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// StoreDir uploads the local directory tree at localDir to remoteDir,
+// creating remote directories as needed. Files other than regular files and
+// directories, like symbolic links, are skipped. If the server advertises
+// MFMT, the modification times of the files are preserved.
+func (c *Client) StoreDir(ctx context.Context, localDir, remoteDir string) error {
+	setModTime, err := c.supports(ctx, "MFMT")
+	if err != nil {
+		return err
+	}
+	return filepath.WalkDir(localDir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(localDir, name)
+		if err != nil {
+			return err
+		}
+		remote := path.Join(remoteDir, filepath.ToSlash(rel))
+		switch {
+		case d.IsDir():
+			return c.makeDir(ctx, remote)
+		case d.Type().IsRegular():
+			return c.storeLocalFile(ctx, name, remote, setModTime)
+		}
+		return nil
+	})
+}
+
+// makeDir creates the directory at path. Most servers reply with
+// CodeFileUnavailable if the directory already exists, so that reply
+// is ignored; if the directory doesn't exist, storing files in it fails.
+func (c *Client) makeDir(ctx context.Context, path string) error {
+	_, err := c.MakeDir(ctx, path)
+	if reply, ok := err.(Reply); ok && reply.Code == CodeFileUnavailable {
+		return nil
+	}
+	return err
+}
+
+// storeLocalFile uploads the local file name to remote.
+func (c *Client) storeLocalFile(ctx context.Context, name, remote string, setModTime bool) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := c.StoreFile(ctx, remote, f); err != nil {
+		return err
+	}
+	if !setModTime {
+		return nil
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return c.SetModTime(ctx, remote, fi.ModTime())
+}
+
+// RetrieveDir downloads the remote directory tree at remoteDir to localDir
+// using Walk, creating local directories as needed. Entries other than files
+// and directories are skipped, including symbolic links unless
+// FollowSymlinks is set. The modification times of the files are
+// preserved if the server lists them.
+func (c *Client) RetrieveDir(ctx context.Context, remoteDir, localDir string) error {
+	return c.Walk(ctx, remoteDir, func(name string, entry Entry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel := filepath.FromSlash(relPath(remoteDir, name))
+		if !filepath.IsLocal(rel) {
+			return fmt.Errorf("ftp: invalid entry name %q", entry.Name)
+		}
+		local := filepath.Join(localDir, rel)
+		switch entry.Type {
+		case EntryDir:
+			return os.MkdirAll(local, 0777)
+		case EntryFile:
+			return c.retrieveLocalFile(ctx, name, local, entry.ModTime)
+		}
+		return nil
+	})
+}
+
+// relPath returns name, which is root or a path within it, relative to root.
+func relPath(root, name string) string {
+	if name == root {
+		return "."
+	} else if root == "." {
+		return name
+	}
+	return strings.TrimPrefix(name, strings.TrimSuffix(root, "/")+"/")
+}
+
+// retrieveLocalFile downloads the file at remote to the local file name.
+func (c *Client) retrieveLocalFile(ctx context.Context, remote, name string, modTime time.Time) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = c.RetrieveFile(ctx, remote, f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil || modTime.IsZero() {
+		return err
+	}
+	return os.Chtimes(name, modTime, modTime)
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStoreDir(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("up/c.txt", []byte("old"))
+	c := s.dial(ctx)
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"a/b.txt": "Hello",
+		"c.txt":   "World",
+	}
+	for name, data := range files {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := c.StoreDir(ctx, dir, "up"); err != nil {
+		t.Fatal(err)
+	}
+	for name, expected := range files {
+		if b, _ := s.file("up/" + name); string(b) != expected {
+			t.Errorf("%s = %q (expected %q)", name, b, expected)
+		}
+	}
+	if n := s.count("MKD"); n != 2 {
+		t.Errorf("MKD sent %d times (expected 2)", n)
+	}
+	if n := s.count("MFMT"); n != len(files) {
+		t.Errorf("MFMT sent %d times (expected %d)", n, len(files))
+	}
+}
+
+func TestRetrieveDir(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	files := map[string]string{
+		"a/b.txt":   "Hello",
+		"a/c/d.txt": "World",
+		"e.txt":     "!",
+	}
+	for name, data := range files {
+		s.setFile("down/"+name, []byte(data))
+	}
+	s.setFile("other.txt", nil)
+	c := s.dial(ctx)
+
+	dir := t.TempDir()
+	if err := c.RetrieveDir(ctx, "down", dir); err != nil {
+		t.Fatal(err)
+	}
+	for name, expected := range files {
+		b, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Error(err)
+		} else if string(b) != expected {
+			t.Errorf("%s = %q (expected %q)", name, b, expected)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "other.txt")); err == nil {
+		t.Error("file outside remote directory retrieved")
+	}
+}
+
+func TestRelPath(t *testing.T) {
+	tests := []struct {
+		root, name string
+		expected   string
+	}{
+		{".", ".", "."},
+		{".", "a/b", "a/b"},
+		{"/", "/a/b", "a/b"},
+		{"down", "down/a", "a"},
+		{"down/", "down/a", "a"},
+	}
+	for i, test := range tests {
+		if rel := relPath(test.root, test.name); rel != test.expected {
+			t.Errorf("tests[%d]: expected %#v (got %#v)", i, test.expected, rel)
+		}
+	}
+}
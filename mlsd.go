@@ -0,0 +1,229 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EntryType is the type of a directory entry.
+type EntryType string
+
+// Entry types defined in RFC 3659.
+const (
+	EntryFile       EntryType = "file"
+	EntryDir        EntryType = "dir"
+	EntryCurrentDir EntryType = "cdir"
+	EntryParentDir  EntryType = "pdir"
+)
+
+// An Entry describes a file or directory on the server.
+type Entry struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+	Type    EntryType
+	Perm    string
+
+	// Symlink reports whether the entry is a symbolic link, as indicated by
+	// the type OS.unix=slink or OS.unix=symlink in MLSx listings, or by the
+	// mode in LIST listings. Target is the target of the link, if listed.
+	Symlink bool
+	Target  string
+
+	// Facts holds all facts reported by the server, including the ones
+	// parsed into the fields above, keyed by their lower-case name.
+	Facts map[string]string
+
+	// Raw is the unparsed line of a LIST-style listing.
+	Raw string
+}
+
+// IsDir reports whether e describes a directory.
+func (e Entry) IsDir() bool {
+	return e.Type == EntryDir || e.Type == EntryCurrentDir || e.Type == EntryParentDir
+}
+
+// MLSD lists the directory at path using the MLSD command defined in RFC 3659.
+// An empty path lists the current directory.
+func (c *Client) MLSD(ctx context.Context, path string) ([]Entry, error) {
+	lines, err := c.textLines(ctx, withPath("MLSD", path))
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(lines))
+	for _, line := range lines {
+		e, err := parseEntry(line)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// ListDirs is like MLSD, but only returns the subdirectories,
+// excluding the cdir and pdir entries.
+func (c *Client) ListDirs(ctx context.Context, path string) ([]Entry, error) {
+	return c.listType(ctx, path, EntryDir)
+}
+
+// ListFiles is like MLSD, but only returns the files.
+func (c *Client) ListFiles(ctx context.Context, path string) ([]Entry, error) {
+	return c.listType(ctx, path, EntryFile)
+}
+
+// listType lists the directory at path using MLSD
+// and returns the entries of type t.
+func (c *Client) listType(ctx context.Context, path string, t EntryType) ([]Entry, error) {
+	entries, err := c.MLSD(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	var filtered []Entry
+	for _, e := range entries {
+		if e.Type == t {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered, nil
+}
+
+// parseEntry parses a line of MLSD or MLST output:
+//
+//	fact=value;fact=value; name
+func parseEntry(line string) (Entry, error) {
+	i := strings.IndexByte(line, ' ')
+	if i == -1 {
+		return Entry{}, errors.New("ftp: malformed MLSx entry: " + line)
+	}
+	e := Entry{
+		Name:  line[i+1:],
+		Facts: make(map[string]string),
+	}
+	for _, fact := range strings.Split(line[:i], ";") {
+		if fact == "" {
+			continue
+		}
+		j := strings.IndexByte(fact, '=')
+		if j == -1 {
+			return Entry{}, errors.New("ftp: malformed MLSx fact: " + fact)
+		}
+		name, value := strings.ToLower(fact[:j]), fact[j+1:]
+		e.Facts[name] = value
+
+		var err error
+		switch name {
+		case "type":
+			typ, target, ok := strings.Cut(value, ":")
+			e.Type = EntryType(strings.ToLower(typ))
+			switch e.Type {
+			case "os.unix=slink", "os.unix=symlink":
+				e.Symlink = true
+				if ok {
+					e.Target = target
+				}
+			default:
+				e.Type = EntryType(strings.ToLower(value))
+			}
+		case "size":
+			e.Size, err = strconv.ParseInt(value, 10, 64)
+		case "modify":
+			e.ModTime, err = parseTimeVal(value)
+		case "perm":
+			e.Perm = value
+		}
+		if err != nil {
+			return Entry{}, err
+		}
+	}
+	return e, nil
+}
+
+// parseTimeVal parses a time-val as defined in RFC 3659:
+//
+//	YYYYMMDDHHMMSS[.sss]
+//
+// The time is always in UTC.
+func parseTimeVal(s string) (time.Time, error) {
+	const layout = "20060102150405"
+	if len(s) < len(layout) {
+		return time.Time{}, errors.New("ftp: malformed time-val: " + s)
+	}
+	t, err := time.ParseInLocation(layout, s[:len(layout)], time.UTC)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if frac := s[len(layout):]; frac != "" {
+		if frac[0] != '.' || len(frac) == 1 {
+			return time.Time{}, errors.New("ftp: malformed time-val: " + s)
+		}
+		digits := frac[1:]
+		if len(digits) > 9 {
+			digits = digits[:9]
+		}
+		n, err := strconv.ParseUint(digits, 10, 32)
+		if err != nil {
+			return time.Time{}, err
+		}
+		for i := len(digits); i < 9; i++ {
+			n *= 10
+		}
+		t = t.Add(time.Duration(n))
+	}
+	return t, nil
+}
+
+// MLST returns the facts of the file or directory at path using the MLST
+// command defined in RFC 3659. Unlike MLSD, the facts are returned over the
+// control connection. If the server doesn't advertise MLST, an error wrapping
+// ErrUnsupported is returned.
+func (c *Client) MLST(ctx context.Context, path string) (Entry, error) {
+	if ok, err := c.supports(ctx, "MLST"); err != nil {
+		return Entry{}, err
+	} else if !ok {
+		return Entry{}, unsupported("MLST")
+	}
+	reply, err := c.sendCommand(ctx, withPath("MLST", path))
+	if err != nil {
+		return Entry{}, err
+	} else if !reply.PositiveComplete() {
+		return Entry{}, reply
+	}
+	lines := reply.Lines()
+	if len(lines) < 3 {
+		return Entry{}, errors.New("ftp: MLST reply provided no entry")
+	}
+	return parseEntry(strings.TrimPrefix(lines[1], " "))
+}
+
+// SetMLSTFacts selects the facts returned by MLST and MLSD using
+// OPTS MLST as defined in RFC 3659, for example to request only "type"
+// and "size", or the nonstandard "unix.mode". Each fact must be advertised
+// by the server in its MLST feature, otherwise an error wrapping
+// ErrUnsupported is returned without changing the selection. An empty
+// list selects no facts at all.
+func (c *Client) SetMLSTFacts(ctx context.Context, facts []string) error {
+	features, err := c.Features(ctx)
+	if err != nil {
+		return err
+	} else if !features.Supports("MLST") {
+		return unsupported("MLST")
+	}
+	advertised := make(map[string]bool)
+	for _, f := range strings.Split(features.Param("MLST"), ";") {
+		advertised[strings.ToLower(strings.TrimSuffix(f, "*"))] = true
+	}
+	var arg strings.Builder
+	for _, f := range facts {
+		if f == "" || !advertised[strings.ToLower(f)] {
+			return unsupported("MLST fact " + f)
+		}
+		arg.WriteString(f + ";")
+	}
+	return c.simpleCommand(ctx, withPath("OPTS MLST", arg.String()))
+}
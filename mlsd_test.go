@@ -0,0 +1,179 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseEntry(t *testing.T) {
+	tests := []struct {
+		Input string
+		Entry Entry
+	}{
+		{
+			"Type=file;Size=1024;Modify=20200102030405;Perm=rw; hello world.txt",
+			Entry{
+				Name:    "hello world.txt",
+				Size:    1024,
+				ModTime: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+				Type:    EntryFile,
+				Perm:    "rw",
+				Facts: map[string]string{
+					"type":   "file",
+					"size":   "1024",
+					"modify": "20200102030405",
+					"perm":   "rw",
+				},
+			},
+		},
+		{
+			"type=cdir;unique=AB12;UNIX.mode=0755; /pub",
+			Entry{
+				Name: "/pub",
+				Type: EntryCurrentDir,
+				Facts: map[string]string{
+					"type":      "cdir",
+					"unique":    "AB12",
+					"unix.mode": "0755",
+				},
+			},
+		},
+		{
+			"type=OS.unix=slink:/pub/Target;size=6; link",
+			Entry{
+				Name:    "link",
+				Size:    6,
+				Type:    "os.unix=slink",
+				Symlink: true,
+				Target:  "/pub/Target",
+				Facts: map[string]string{
+					"type": "OS.unix=slink:/pub/Target",
+					"size": "6",
+				},
+			},
+		},
+		{
+			" noFacts",
+			Entry{Name: "noFacts", Facts: map[string]string{}},
+		},
+	}
+	for i, tt := range tests {
+		e, err := parseEntry(tt.Input)
+		if err != nil {
+			t.Errorf("tests[%d] error: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(tt.Entry, e) {
+			t.Errorf("tests[%d]: expected %#v (got %#v)", i, tt.Entry, e)
+		}
+	}
+}
+
+func TestParseTimeVal(t *testing.T) {
+	tests := []struct {
+		Input string
+		Time  time.Time
+	}{
+		{"20200102030405", time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"20200102030405.5", time.Date(2020, 1, 2, 3, 4, 5, 500e6, time.UTC)},
+		{"20200102030405.123", time.Date(2020, 1, 2, 3, 4, 5, 123e6, time.UTC)},
+	}
+	for i, tt := range tests {
+		tm, err := parseTimeVal(tt.Input)
+		if err != nil {
+			t.Errorf("tests[%d] error: %v", i, err)
+			continue
+		}
+		if !tm.Equal(tt.Time) {
+			t.Errorf("tests[%d]: expected %v (got %v)", i, tt.Time, tm)
+		}
+	}
+
+	for _, s := range []string{"2020", "20200102030405.", "20201302030405", "20200102030405x"} {
+		if _, err := parseTimeVal(s); err == nil {
+			t.Errorf("parseTimeVal(%q): expected error", s)
+		}
+	}
+}
+
+func TestMLST(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("hello.txt", []byte("Hello, World\n"))
+	c := s.dial(ctx)
+
+	e, err := c.MLST(ctx, "hello.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Name != "hello.txt" || e.Type != EntryFile || e.Size != 13 {
+		t.Errorf("entry = %#v", e)
+	}
+
+	s.setFeatures()
+	c = s.dial(ctx) // features are cached per connection
+	if _, err := c.MLST(ctx, "hello.txt"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("err = %v (expected %v)", err, ErrUnsupported)
+	}
+}
+
+func TestListDirsFiles(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("pub/hello.txt", nil)
+	s.setFile("src/main.go", nil)
+	s.setFile("readme.txt", nil)
+	c := s.dial(ctx)
+
+	tests := []struct {
+		List     func(context.Context, string) ([]Entry, error)
+		Expected []string
+	}{
+		{c.ListDirs, []string{"pub", "src"}},
+		{c.ListFiles, []string{"readme.txt"}},
+	}
+	for i, tt := range tests {
+		entries, err := tt.List(ctx, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name)
+		}
+		slices.Sort(names)
+		if !reflect.DeepEqual(names, tt.Expected) {
+			t.Errorf("tests[%d]: expected %q (got %q)", i, tt.Expected, names)
+		}
+	}
+}
+
+func TestSetMLSTFacts(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	var opts []string
+	s.handle("OPTS", func(sc *serverConn, arg string) {
+		opts = append(opts, arg)
+		sc.reply(200, "Okay")
+	})
+	c := s.dial(ctx)
+
+	if err := c.SetMLSTFacts(ctx, []string{"Type", "size"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetMLSTFacts(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetMLSTFacts(ctx, []string{"type", "unix.mode"}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("err = %v (expected %v)", err, ErrUnsupported)
+	}
+	if expected := []string{"MLST Type;size;", "MLST"}; !reflect.DeepEqual(opts, expected) {
+		t.Errorf("OPTS %q (expected %q)", opts, expected)
+	}
+}
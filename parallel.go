@@ -0,0 +1,98 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// ParallelRetrieve retrieves the file at path of size bytes in image mode
+// and writes it to out, using segments concurrent transfers of Clients from
+// pool. Each transfer is restarted at the offset of its segment using REST,
+// and all but the last are aborted once their segment is complete. This
+// speeds up retrieving large files over links with high latency.
+//
+// If the server doesn't advertise REST, an error wrapping ErrUnsupported is
+// returned. The pool should hold at least segments Clients to retrieve the
+// segments concurrently.
+func ParallelRetrieve(ctx context.Context, pool *Pool, path string, size int64, out io.WriterAt, segments int) error {
+	if segments < 1 {
+		return errors.New("ftp: number of segments must be positive")
+	}
+	c, err := pool.Get(ctx)
+	if err != nil {
+		return err
+	}
+	ok, err := c.supports(ctx, "REST")
+	pool.Put(c)
+	if err != nil {
+		return err
+	} else if !ok {
+		return unsupported("REST")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	segSize := (size + int64(segments) - 1) / int64(segments)
+	for offset := int64(0); offset < size; offset += segSize {
+		n := min(segSize, size-offset)
+		wg.Add(1)
+		go func(offset, n int64) {
+			defer wg.Done()
+			if err := retrieveSegment(ctx, pool, path, offset, n, offset+n == size, out); err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(offset, n)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// retrieveSegment retrieves n bytes of the file at path starting at offset
+// and writes them to out at the same offset. Unless last is set, the
+// transfer is aborted after n bytes.
+func retrieveSegment(ctx context.Context, pool *Pool, path string, offset, n int64, last bool, out io.WriterAt) (err error) {
+	c, err := pool.Get(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			c.Close() // state unknown, the pool redials
+		}
+		pool.Put(c)
+	}()
+
+	_, conn, err := c.transfer(ctx, "RETR "+path, "I", offset)
+	if err != nil {
+		return err
+	}
+	w := io.NewOffsetWriter(out, offset)
+	if last {
+		_, err = c.copy(w, conn)
+		if cerr := conn.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	}
+	written, err := c.copy(w, io.LimitReader(conn, n))
+	if err == nil && written < n {
+		err = io.EOF
+	}
+	if aerr := c.Abort(ctx); err == nil {
+		err = aerr
+	}
+	return err
+}
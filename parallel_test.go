@@ -0,0 +1,55 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+type writerAt []byte
+
+func (w writerAt) WriteAt(p []byte, off int64) (int, error) {
+	return copy(w[off:], p), nil
+}
+
+func TestParallelRetrieve(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFeatures("REST STREAM")
+	expected := bytes.Repeat([]byte("0123456789"), 1000)
+	s.setFile("big.bin", expected)
+	p, err := NewPool(ctx, "tcp", s.ln.Addr().String(), "user", "pass", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	for _, segments := range []int{1, 3, 4, 7} {
+		out := make(writerAt, len(expected))
+		if err := ParallelRetrieve(ctx, p, "big.bin", int64(len(expected)), out, segments); err != nil {
+			t.Fatalf("segments = %d: %v", segments, err)
+		}
+		if !bytes.Equal(out, expected) {
+			t.Errorf("segments = %d: data mismatch", segments)
+		}
+	}
+}
+
+func TestParallelRetrieveUnsupported(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFeatures()
+	p, err := NewPool(ctx, "tcp", s.ln.Addr().String(), "user", "pass", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	err = ParallelRetrieve(ctx, p, "big.bin", 10, make(writerAt, 10), 2)
+	if !errors.Is(err, ErrUnsupported) {
+		t.Errorf("err = %v (expected %v)", err, ErrUnsupported)
+	}
+}
@@ -6,6 +6,7 @@ package ftp
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net"
 	"regexp"
 	"strconv"
@@ -14,31 +15,108 @@ import (
 
 // openPassive creates a new passive data connection.
 func (c *Client) openPassive(ctx context.Context) (net.Conn, error) {
-	addr, err := c.obtainPassiveAddress(ctx)
+	addr, err := c.passiveAddr(ctx)
 	if err != nil {
 		return nil, err
 	}
-	var d net.Dialer
-	return d.DialContext(ctx, addr.Network(), addr.String())
+	dial := c.DialFunc
+	if dial == nil {
+		var d net.Dialer
+		dial = d.DialContext
+	}
+	dctx := ctx
+	if c.DataConnectTimeout > 0 {
+		var cancel context.CancelFunc
+		dctx, cancel = context.WithTimeout(ctx, c.DataConnectTimeout)
+		defer cancel()
+	}
+	conn, err := dial(dctx, addr.Network(), addr.String())
+	if err != nil {
+		// Tell which address was dialed and how it was obtained,
+		// because failures are often caused by NAT.
+		desc := "data connection"
+		if cmd := c.lastDataAddr.Command; cmd != "" {
+			desc = cmd + " " + desc
+		}
+		return nil, fmt.Errorf("ftp: %s to %v: %w", desc, addr, err)
+	}
+	if err := c.TCPOptions.apply(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c.protectData(conn), nil
+}
+
+// passiveAddr returns the address to dial for a new passive data
+// connection using PassiveAddrFunc, or PassiveAddr if it isn't set, and
+// records it for LastDataAddr.
+func (c *Client) passiveAddr(ctx context.Context) (*net.TCPAddr, error) {
+	if c.PassiveAddrFunc == nil {
+		return c.PassiveAddr(ctx)
+	}
+	c.lastDataAddr = nil
+	addr, err := c.PassiveAddrFunc(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	// Keep the command if the address was derived from PassiveAddr.
+	da := &DataAddr{TCPAddr: *addr}
+	if c.lastDataAddr != nil {
+		da.Command = c.lastDataAddr.Command
+	}
+	c.lastDataAddr = da
+	return addr, nil
 }
 
-// obtainPassiveAddress returns the address to dial
-// for a new passive data connection.
-func (c *Client) obtainPassiveAddress(ctx context.Context) (*net.TCPAddr, error) {
-	if c.conn.RemoteAddr().Network() == "tcp6" {
-		c.obtainPassiveAddress6(ctx)
+// PassiveAddr returns the address to dial for a new passive data
+// connection by negotiating it with the server, which is what passive
+// data connections use unless PassiveAddrFunc is set.
+//
+// EPSV is preferred, because it reuses the IP address of the control
+// connection instead of trusting the address advertised by PASV, which is
+// often unroutable behind NAT. On IPv4, if the server rejects EPSV, PASV is
+// used instead for this and all following data connections, unless
+// ForceEPSV is set. If ForcePASV is set, or the IP address of a server
+// dialed through a proxy is unknown, PASV is used right away.
+func (c *Client) PassiveAddr(ctx context.Context) (*net.TCPAddr, error) {
+	// PASV can't describe IPv6 addresses. The network of the remote
+	// address is "tcp" for both IPv4 and IPv6, so check the IP itself.
+	ip, err := c.remoteIP()
+	if c.ForceEPSV || err == nil && ip.To4() == nil {
+		return c.obtainEpsvAddress(ctx)
 	}
-	return c.obtainPassiveAddress4(ctx)
+	if err == nil && !c.epsvFailed && !c.ForcePASV {
+		addr, err := c.obtainEpsvAddress(ctx)
+		if reply, ok := err.(Reply); !ok || reply.Code/100 != 5 {
+			return addr, err
+		}
+		c.epsvFailed = true
+	}
+	return c.obtainPasvAddress(ctx)
 }
 
-func (c *Client) obtainPassiveAddress4(ctx context.Context) (*net.TCPAddr, error) {
+func (c *Client) obtainPasvAddress(ctx context.Context) (*net.TCPAddr, error) {
 	reply, err := c.sendCommand(ctx, "PASV")
 	if err != nil {
 		return nil, err
 	} else if reply.Code != CodePassive {
 		return nil, reply
 	}
-	return parsePasvReply(reply.Msg)
+	addr, err := parsePasvReply(reply.Msg)
+	if err != nil {
+		return nil, err
+	}
+	if c.serverHost != "" && c.serverIP == nil {
+		c.serverIP = addr.IP
+	}
+	if c.IgnorePASVAddress {
+		addr.IP, err = c.remoteIP()
+		if err != nil {
+			return nil, err
+		}
+	}
+	c.lastDataAddr = &DataAddr{TCPAddr: *addr, Command: "PASV"}
+	return addr, nil
 }
 
 var pasvRegexp = regexp.MustCompile(`([0-9]+),([0-9]+),([0-9]+),([0-9]+),([0-9]+),([0-9]+)`)
@@ -48,18 +126,21 @@ func parsePasvReply(msg string) (*net.TCPAddr, error) {
 	if numberStrings == nil {
 		return nil, errors.New("PASV reply provided no port")
 	}
-	numbers := make([]byte, len(numberStrings))
-	for i, s := range numberStrings {
-		n, _ := strconv.Atoi(s)
-		numbers[i] = byte(n)
+	var numbers [6]int
+	for i, s := range numberStrings[1:] {
+		n, err := strconv.Atoi(s)
+		if err != nil || n > 255 {
+			return nil, errors.New("PASV reply contains invalid number " + s)
+		}
+		numbers[i] = n
 	}
 	return &net.TCPAddr{
-		IP:   net.IP(numbers[1:5]),
-		Port: int(numbers[5])<<8 | int(numbers[6]),
+		IP:   net.IPv4(byte(numbers[0]), byte(numbers[1]), byte(numbers[2]), byte(numbers[3])),
+		Port: numbers[4]<<8 | numbers[5],
 	}, nil
 }
 
-func (c *Client) obtainPassiveAddress6(ctx context.Context) (*net.TCPAddr, error) {
+func (c *Client) obtainEpsvAddress(ctx context.Context) (*net.TCPAddr, error) {
 	reply, err := c.sendCommand(ctx, "EPSV")
 	if err != nil {
 		return nil, err
@@ -72,28 +153,65 @@ func (c *Client) obtainPassiveAddress6(ctx context.Context) (*net.TCPAddr, error
 		return nil, err
 	}
 
-	return &net.TCPAddr{
-		IP:   c.conn.RemoteAddr().(*net.TCPAddr).IP,
-		Port: port,
-	}, nil
+	ip, err := c.remoteIP()
+	if err != nil {
+		return nil, err
+	}
+	addr := &net.TCPAddr{IP: ip, Port: port}
+	c.lastDataAddr = &DataAddr{TCPAddr: *addr, Command: "EPSV"}
+	return addr, nil
 }
 
-const (
-	epsvStart = "(|||"
-	epsvEnd   = "|)"
-)
-
+// parseEpsvReply parses the port from an EPSV reply as defined in
+// RFC 2428 section 3:
+//
+//	(<d><d><d><tcp-port><d>)
+//
+// The delimiter <d> is chosen by the server and is usually '|'.
 func parseEpsvReply(msg string) (port int, err error) {
-	start := strings.LastIndex(msg, epsvStart)
-	if start == -1 {
+	start := strings.LastIndexByte(msg, '(')
+	if start == -1 || len(msg) < start+6 {
 		return 0, errors.New("EPSV reply provided no port")
 	}
-	start += len(epsvStart)
-
-	end := strings.LastIndex(msg, epsvEnd)
-	if end == -1 || end <= start {
+	d := msg[start+1]
+	rest := msg[start+1:]
+	if rest[1] != d || rest[2] != d {
 		return 0, errors.New("EPSV reply provided no port")
 	}
+	rest = rest[3:]
+	end := strings.IndexByte(rest, d)
+	if end <= 0 || !strings.HasPrefix(rest[end+1:], ")") {
+		return 0, errors.New("EPSV reply provided no port")
+	}
+	port, err = strconv.Atoi(rest[:end])
+	if err != nil || port < 1 || port > 65535 {
+		return 0, errors.New("EPSV reply contains invalid port " + rest[:end])
+	}
+	return port, nil
+}
 
-	return strconv.Atoi(msg[start:end])
+// remoteIP returns the IP address of the server on the control connection.
+func (c *Client) remoteIP() (net.IP, error) {
+	if c.serverIP != nil {
+		return c.serverIP, nil
+	} else if c.serverHost != "" {
+		return nil, errors.New("ftp: IP address of " + c.serverHost + " is unknown")
+	}
+	return addrIP(c.conn.RemoteAddr())
+}
+
+// addrIP returns the IP address of addr.
+func addrIP(addr net.Addr) (net.IP, error) {
+	if addr, ok := addr.(*net.TCPAddr); ok {
+		return addr.IP, nil
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return nil, err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, errors.New("ftp: address is not an IP address: " + host)
+	}
+	return ip, nil
 }
@@ -4,8 +4,15 @@
 package ftp
 
 import (
+	"context"
+	"errors"
+	"io"
 	"net"
+	"net/textproto"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestParsePasvReply(t *testing.T) {
@@ -26,13 +33,404 @@ func TestParsePasvReply(t *testing.T) {
 	}
 }
 
+func TestParsePasvReplyMalformed(t *testing.T) {
+	tests := []string{
+		"227 Entering Passive Mode.",
+		"227 Entering Passive Mode. 192,0,2,47,4",
+		"227 Entering Passive Mode. 999,0,2,47,4,7",
+		"227 Entering Passive Mode. 192,0,2,47,256,7",
+		"227 Entering Passive Mode. 192,0,2,47,4,99999999999999999999",
+	}
+	for i, msg := range tests {
+		if addr, err := parsePasvReply(msg); err == nil {
+			t.Errorf("tests[%d]: expected error (got %v)", i, addr)
+		}
+	}
+}
+
 func TestEpsvReply(t *testing.T) {
-	const expectedPort = 1031
-	port, err := parseEpsvReply("229 Entering Extended Passive Mode. (|||1031|)")
+	tests := []struct {
+		Msg  string
+		Port int
+	}{
+		{"Entering Extended Passive Mode. (|||1031|)", 1031},
+		{"Entering Extended Passive Mode (!!!1031!)", 1031},
+		{"Entering Extended Passive Mode (###65535#)", 65535},
+		{"Entering (not so) Extended Passive Mode (|||21|)", 21},
+	}
+	for i, tt := range tests {
+		port, err := parseEpsvReply(tt.Msg)
+		if err != nil {
+			t.Errorf("tests[%d] error: %v", i, err)
+			continue
+		}
+		if port != tt.Port {
+			t.Errorf("tests[%d]: expected %#v (got %#v)", i, tt.Port, port)
+		}
+	}
+
+	for i, msg := range []string{
+		"Entering Extended Passive Mode",
+		"Entering Extended Passive Mode (|||1031)",
+		"Entering Extended Passive Mode (||!1031|)",
+		"Entering Extended Passive Mode (||||)",
+		"Entering Extended Passive Mode (|||x|)",
+		"Entering Extended Passive Mode (|||70000|)",
+		"(|||",
+	} {
+		if port, err := parseEpsvReply(msg); err == nil {
+			t.Errorf("tests[%d]: expected error (got %v)", i, port)
+		}
+	}
+}
+
+// testAddr is a net.Addr with an arbitrary network.
+type testAddr struct {
+	network, addr string
+}
+
+func (a testAddr) Network() string { return a.network }
+func (a testAddr) String() string  { return a.addr }
+
+// addrConn overrides the remote address of a net.Conn.
+type addrConn struct {
+	net.Conn
+	remote net.Addr
+}
+
+func (c addrConn) RemoteAddr() net.Addr { return c.remote }
+
+func TestObtainPassiveAddress6(t *testing.T) {
+	var (
+		expectedIP   = net.ParseIP("2001:db8::1")
+		expectedPort = 1031
+	)
+
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	go func() {
+		proto := textproto.NewConn(server)
+		line, _ := proto.ReadLine()
+		if line != "EPSV" {
+			proto.PrintfLine("500 Unexpected command %s", line)
+			return
+		}
+		proto.PrintfLine("229 Entering Extended Passive Mode (|||%d|)", expectedPort)
+	}()
+
+	c := &Client{
+		conn:  addrConn{client, &net.TCPAddr{IP: expectedIP, Port: 21}},
+		proto: textproto.NewConn(client),
+	}
+	addr, err := c.PassiveAddr(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
-	if port != expectedPort {
-		t.Errorf("port = %v (expected %v)", port, expectedPort)
+	if !addr.IP.Equal(expectedIP) {
+		t.Errorf("addr.IP = %v (expected %v)", addr.IP, expectedIP)
+	}
+	if addr.Port != expectedPort {
+		t.Errorf("addr.Port = %v (expected %v)", addr.Port, expectedPort)
+	}
+}
+
+func TestObtainPassiveAddress6NoFallback(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	lines := make(chan string, 2)
+	go func() {
+		proto := textproto.NewConn(server)
+		for {
+			line, err := proto.ReadLine()
+			if err != nil {
+				close(lines)
+				return
+			}
+			lines <- line
+			proto.PrintfLine("500 Unknown command")
+		}
+	}()
+
+	c := &Client{
+		conn:  addrConn{client, &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 21}},
+		proto: textproto.NewConn(client),
+	}
+	if _, err := c.PassiveAddr(context.Background()); err == nil {
+		t.Fatal("expected error")
+	}
+	client.Close()
+	var sent []string
+	for line := range lines {
+		sent = append(sent, line)
+	}
+	if len(sent) != 1 || sent[0] != "EPSV" {
+		t.Errorf("sent %q (expected only EPSV)", sent)
+	}
+}
+
+func TestPassiveFallback(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("hello.txt", []byte("Hello, World\n"))
+	s.handle("EPSV", func(sc *serverConn, arg string) {
+		sc.reply(500, "Unknown command")
+	})
+	c := s.dial(ctx)
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.RetrieveFile(ctx, "hello.txt", io.Discard); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := s.count("EPSV"); n != 1 {
+		t.Errorf("EPSV sent %d times (expected 1)", n)
+	}
+	if n := s.count("PASV"); n != 2 {
+		t.Errorf("PASV sent %d times (expected 2)", n)
+	}
+}
+
+func TestForcePassiveCommand(t *testing.T) {
+	tests := []struct {
+		ForcePASV, ForceEPSV bool
+		EPSV, PASV           int
+	}{
+		{false, false, 1, 0},
+		{true, false, 0, 1},
+		{false, true, 1, 0},
+		{true, true, 1, 0},
+	}
+	ctx := context.Background()
+	for i, tt := range tests {
+		s := newTestServer(t)
+		s.setFile("hello.txt", []byte("Hello, World\n"))
+		c := s.dial(ctx)
+		c.ForcePASV, c.ForceEPSV = tt.ForcePASV, tt.ForceEPSV
+		if _, err := c.RetrieveFile(ctx, "hello.txt", io.Discard); err != nil {
+			t.Fatal(err)
+		}
+		if epsv, pasv := s.count("EPSV"), s.count("PASV"); epsv != tt.EPSV || pasv != tt.PASV {
+			t.Errorf("tests[%d]: expected EPSV %d, PASV %d (got %d, %d)", i, tt.EPSV, tt.PASV, epsv, pasv)
+		}
+	}
+
+	// ForceEPSV doesn't fall back to PASV.
+	s := newTestServer(t)
+	s.handle("EPSV", func(sc *serverConn, arg string) {
+		sc.reply(500, "Unknown command")
+	})
+	c := s.dial(ctx)
+	c.ForceEPSV = true
+	if _, err := c.RetrieveFile(ctx, "hello.txt", io.Discard); err == nil {
+		t.Error("expected error")
+	}
+	if n := s.count("PASV"); n != 0 {
+		t.Errorf("PASV sent %d times (expected 0)", n)
+	}
+}
+
+func TestIgnorePASVAddress(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("hello.txt", []byte("Hello, World\n"))
+	s.handle("EPSV", func(sc *serverConn, arg string) {
+		sc.reply(502, "Command not implemented")
+	})
+	s.handle("PASV", func(sc *serverConn, arg string) {
+		sc.listenPassive()
+		port := sc.pasv.Addr().(*net.TCPAddr).Port
+		sc.reply(227, "Entering Passive Mode (192,0,2,47,%d,%d)", port>>8, port&0xff)
+	})
+	c := s.dial(ctx)
+	c.IgnorePASVAddress = true
+
+	if _, err := c.RetrieveFile(ctx, "hello.txt", io.Discard); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDialFunc(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("hello.txt", []byte("Hello, World\n"))
+	c := s.dial(ctx)
+
+	var dialed []string
+	c.DialFunc = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialed = append(dialed, addr)
+		var d net.Dialer
+		return d.DialContext(ctx, network, addr)
+	}
+	if _, err := c.RetrieveFile(ctx, "hello.txt", io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if len(dialed) != 1 {
+		t.Errorf("dialed %q (expected one data connection)", dialed)
+	}
+}
+
+func TestLastDataAddr(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("hello.txt", []byte("Hello, World\n"))
+	c := s.dial(ctx)
+
+	if addr := c.LastDataAddr(); addr != nil {
+		t.Errorf("addr = %v (expected nil)", addr)
+	}
+	if _, err := c.RetrieveFile(ctx, "hello.txt", io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	addr := c.LastDataAddr()
+	if addr == nil || addr.Command != "EPSV" || !addr.IP.Equal(net.IPv4(127, 0, 0, 1)) {
+		t.Errorf("addr = %+v (expected EPSV address on 127.0.0.1)", addr)
+	}
+}
+
+func TestTCPOptions(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("hello.txt", []byte("Hello, World\n"))
+	for _, keepAlive := range []time.Duration{0, time.Minute, -1} {
+		opts := &TCPOptions{KeepAlive: keepAlive, DisableNoDelay: true}
+		c, err := DialWithOptions(ctx, "tcp", s.ln.Addr().String(), DialOptions{TCPOptions: opts})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.TCPOptions != opts {
+			t.Error("TCPOptions not set for data connections")
+		}
+		if err := c.Login(ctx, "user", "pass"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.RetrieveFile(ctx, "hello.txt", io.Discard); err != nil {
+			t.Errorf("KeepAlive %v: %v", keepAlive, err)
+		}
+		c.Close()
+	}
+
+	// Other connections are left unchanged.
+	conn, peer := net.Pipe()
+	defer conn.Close()
+	defer peer.Close()
+	if err := (&TCPOptions{KeepAlive: time.Minute}).apply(conn); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPRET(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("hello.txt", []byte("Hello, World\n"))
+	s.handle("PRET", func(sc *serverConn, arg string) {
+		sc.reply(200, "Okay")
+	})
+
+	tests := []struct {
+		Features []string
+		UsePRET  bool
+		Expected []string
+	}{
+		{[]string{"SIZE"}, false, []string{"EPSV", "RETR hello.txt"}},
+		{[]string{"SIZE"}, true, []string{"PRET RETR hello.txt", "EPSV", "RETR hello.txt"}},
+		{[]string{"PRET"}, false, []string{"PRET RETR hello.txt", "EPSV", "RETR hello.txt"}},
+	}
+	for i, tt := range tests {
+		s.setFeatures(tt.Features...)
+		c := s.dial(ctx)
+		c.UsePRET = tt.UsePRET
+		c.Features(ctx)
+		var sent []string
+		c.Logger = func(dir Direction, line string) {
+			if dir == Sent && line != "TYPE I" {
+				sent = append(sent, line)
+			}
+		}
+		if _, err := c.RetrieveFile(ctx, "hello.txt", io.Discard); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(sent, tt.Expected) {
+			t.Errorf("tests[%d]: expected %q (got %q)", i, tt.Expected, sent)
+		}
+	}
+}
+
+func TestDataConnectTimeout(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("hello.txt", []byte("Hello, World\n"))
+	c := s.dial(ctx)
+	c.DataConnectTimeout = 50 * time.Millisecond
+	c.DialFunc = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		<-ctx.Done() // unreachable address
+		return nil, ctx.Err()
+	}
+
+	start := time.Now()
+	if _, err := c.RetrieveFile(ctx, "hello.txt", io.Discard); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v (expected %v)", err, context.DeadlineExceeded)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("RetrieveFile returned after %v", d)
+	}
+	if n := s.count("RETR"); n != 0 {
+		t.Errorf("RETR sent %d times (expected 0)", n)
+	}
+}
+
+func TestDataConnError(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	c := s.dial(ctx)
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	c.DialFunc = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return nil, dialErr
+	}
+
+	_, err := c.RetrieveFile(ctx, "hello.txt", io.Discard)
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) || opErr != dialErr {
+		t.Fatalf("err = %v (expected to wrap %v)", err, dialErr)
+	}
+	addr := c.LastDataAddr()
+	if msg := err.Error(); !strings.Contains(msg, "EPSV") || !strings.Contains(msg, addr.String()) {
+		t.Errorf("err = %q (expected command and address)", msg)
+	}
+}
+
+func TestPassiveAddrFunc(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("hello.txt", []byte("Hello, World\n"))
+	c := s.dial(ctx)
+
+	calls := 0
+	c.PassiveAddrFunc = func(ctx context.Context, c *Client) (*net.TCPAddr, error) {
+		calls++
+		addr, err := c.PassiveAddr(ctx)
+		if err != nil {
+			return nil, err
+		}
+		addr.IP = net.IPv4(127, 0, 0, 1) // always use the control IP
+		return addr, nil
+	}
+	if _, err := c.RetrieveFile(ctx, "hello.txt", io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Errorf("PassiveAddrFunc called %d times (expected 1)", calls)
+	}
+	if addr := c.LastDataAddr(); addr == nil || addr.Command != "EPSV" {
+		t.Errorf("LastDataAddr() = %v (expected EPSV address)", addr)
+	}
+
+	resolveErr := errors.New("no route")
+	c.PassiveAddrFunc = func(ctx context.Context, c *Client) (*net.TCPAddr, error) {
+		return nil, resolveErr
+	}
+	if _, err := c.RetrieveFile(ctx, "hello.txt", io.Discard); err != resolveErr {
+		t.Errorf("err = %v (expected %v)", err, resolveErr)
 	}
 }
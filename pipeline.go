@@ -0,0 +1,70 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrPipeliningDisabled is returned by Pipeline unless
+// Client.EnablePipelining is set.
+var ErrPipeliningDisabled = errors.New("ftp: pipelining not enabled")
+
+// Pipeline sends all commands before reading any of their replies, saving a
+// round trip per command on links with high latency. The replies are
+// returned in the order of the commands, including negative ones; the error
+// is only set if the exchange itself failed, in which case the replies read
+// so far are returned.
+//
+// RFC 959 doesn't forbid pipelining, but many servers don't handle it well
+// and may drop commands or close the connection. Pipeline therefore requires
+// EnablePipelining to be set. Only pipeline independent commands that don't
+// open data connections, like DELE or MKD.
+func (c *Client) Pipeline(ctx context.Context, commands []string) ([]Reply, error) {
+	if !c.EnablePipelining {
+		return nil, ErrPipeliningDisabled
+	}
+	for _, command := range commands {
+		if err := checkLine(command); err != nil {
+			return nil, err
+		}
+	}
+	if ctx.Done() == nil {
+		return c.pipeline(commands)
+	}
+	type result struct {
+		replies []Reply
+		err     error
+	}
+	resc := make(chan result, 1)
+	go func() {
+		replies, err := c.pipeline(commands)
+		resc <- result{replies, err}
+	}()
+	select {
+	case r := <-resc:
+		return r.replies, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *Client) pipeline(commands []string) ([]Reply, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, command := range commands {
+		if err := c.writeLine(command); err != nil {
+			return nil, err
+		}
+	}
+	replies := make([]Reply, 0, len(commands))
+	for range commands {
+		reply, err := c.readResponse()
+		if err != nil {
+			return replies, err
+		}
+		replies = append(replies, reply)
+	}
+	return replies, nil
+}
@@ -0,0 +1,39 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPipeline(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("a.txt", nil)
+	s.setFile("b.txt", nil)
+	c := s.dial(ctx)
+
+	commands := []string{"DELE a.txt", "DELE missing.txt", "DELE b.txt"}
+	if _, err := c.Pipeline(ctx, commands); err != ErrPipeliningDisabled {
+		t.Errorf("err = %v (expected %v)", err, ErrPipeliningDisabled)
+	}
+
+	c.EnablePipelining = true
+	replies, err := c.Pipeline(ctx, commands)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Code{CodeActionOkay, CodeFileUnavailable, CodeActionOkay}
+	if len(replies) != len(expected) {
+		t.Fatalf("got %d replies (expected %d)", len(replies), len(expected))
+	}
+	for i, reply := range replies {
+		if reply.Code != expected[i] {
+			t.Errorf("replies[%d]: expected %v (got %v)", i, expected[i], reply.Code)
+		}
+	}
+	if err := c.NoOp(ctx); err != nil {
+		t.Error("client unusable after pipeline:", err)
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrPoolClosed is returned by Pool.Get after the pool is closed.
+var ErrPoolClosed = errors.New("ftp: pool closed")
+
+// A Pool maintains up to a fixed number of logged in Clients to the same
+// server, so transfers can run concurrently. A Pool is safe for concurrent
+// use by multiple goroutines.
+type Pool struct {
+	network, addr      string
+	username, password string
+
+	sem    chan struct{} // one token per Client in use
+	mu     sync.Mutex
+	idle   []*Client
+	closed bool
+}
+
+// NewPool creates a pool of at most size Clients connected to addr and
+// logged in with username and password. A first Client is connected
+// to verify the address and credentials.
+func NewPool(ctx context.Context, network, addr, username, password string, size int) (*Pool, error) {
+	if size < 1 {
+		return nil, errors.New("ftp: pool size must be positive")
+	}
+	p := &Pool{
+		network:  network,
+		addr:     addr,
+		username: username,
+		password: password,
+		sem:      make(chan struct{}, size),
+	}
+	c, err := p.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.idle = append(p.idle, c)
+	return p, nil
+}
+
+func (p *Pool) dial(ctx context.Context) (*Client, error) {
+	c, err := Dial(ctx, p.network, p.addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Login(ctx, p.username, p.password); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Get returns a Client from the pool, waiting until one is available if
+// all Clients are in use. Idle Clients are checked using NOOP before they
+// are returned; dead connections are closed and replaced by a new one.
+// The Client must be returned to the pool using Put.
+func (p *Pool) Get(ctx context.Context) (*Client, error) {
+	select {
+	case p.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	c, err := p.get(ctx)
+	if err != nil {
+		<-p.sem
+		return nil, err
+	}
+	return c, nil
+}
+
+func (p *Pool) get(ctx context.Context) (*Client, error) {
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, ErrPoolClosed
+		}
+		if len(p.idle) == 0 {
+			p.mu.Unlock()
+			return p.dial(ctx)
+		}
+		c := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		p.mu.Unlock()
+
+		if err := c.NoOp(ctx); err == nil {
+			return c, nil
+		} else if ctx.Err() != nil {
+			c.Close()
+			return nil, err
+		}
+		c.Close()
+	}
+}
+
+// Put returns a Client obtained from Get to the pool. The Client must not
+// have a transfer in progress. If the pool is closed, the Client is closed.
+func (p *Pool) Put(c *Client) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		c.Quit(context.Background())
+	} else {
+		p.idle = append(p.idle, c)
+		p.mu.Unlock()
+	}
+	<-p.sem
+}
+
+// Close closes the idle Clients in the pool. Clients in use are closed
+// when they are returned using Put.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.closed = true
+	p.mu.Unlock()
+
+	var err error
+	for _, c := range idle {
+		if cerr := c.Quit(context.Background()); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPool(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	p, err := NewPool(ctx, "tcp", s.ln.Addr().String(), "user", "pass", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	c1, err := p.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, err := p.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c1 == c2 {
+		t.Error("Get returned the same Client twice")
+	}
+
+	// The pool is exhausted until a Client is returned.
+	tctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := p.Get(tctx); err != context.DeadlineExceeded {
+		t.Errorf("Get = %v (expected %v)", err, context.DeadlineExceeded)
+	}
+
+	p.Put(c1)
+	c3, err := p.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c3 != c1 {
+		t.Error("idle Client not reused")
+	}
+	p.Put(c3)
+	p.Put(c2)
+
+	if n := s.count("PASS"); n != 2 {
+		t.Errorf("logged in %d times (expected 2)", n)
+	}
+}
+
+func TestPoolRedial(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	p, err := NewPool(ctx, "tcp", s.ln.Addr().String(), "user", "pass", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	c, err := p.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	p.Put(c)
+
+	c, err = p.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Put(c)
+	if err := c.NoOp(ctx); err != nil {
+		t.Error("dead Client not replaced:", err)
+	}
+}
+
+func TestPoolClosed(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	p, err := NewPool(ctx, "tcp", s.ln.Addr().String(), "user", "pass", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Get(ctx); err != ErrPoolClosed {
+		t.Errorf("Get = %v (expected %v)", err, ErrPoolClosed)
+	}
+}
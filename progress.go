@@ -0,0 +1,92 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// ProgressFunc is called with the number of bytes transferred so far.
+type ProgressFunc func(n int64)
+
+// progressInterval is the minimum time between calls of a ProgressFunc,
+// except for the final call.
+const progressInterval = 100 * time.Millisecond
+
+// RetrieveFileWithProgress is like RetrieveFile, but calls progress
+// periodically during the transfer and once with the total number of bytes
+// written to w when the transfer completes.
+func (c *Client) RetrieveFileWithProgress(ctx context.Context, path string, w io.Writer, progress ProgressFunc) (int64, error) {
+	p := &progressWriter{w: w, progressCounter: progressCounter{progress: progress}}
+	n, err := c.RetrieveFile(ctx, path, p)
+	progress(n)
+	return n, err
+}
+
+// RetrieveFileWithTotal is like RetrieveFileWithProgress, but also passes
+// the size of the file to progress, so it can show the fraction done. The
+// size is taken from the reply that starts the transfer, see
+// Reply.TransferSize, and is -1 if the server didn't announce it.
+func (c *Client) RetrieveFileWithTotal(ctx context.Context, path string, w io.Writer, progress func(n, total int64)) (int64, error) {
+	total := int64(-1)
+	p := &progressWriter{w: w, progressCounter: progressCounter{progress: func(n int64) {
+		progress(n, total)
+	}}}
+	n, err := c.retrieve(ctx, path, 0, p, func(reply Reply) {
+		if size, ok := reply.TransferSize(); ok {
+			total = size
+		}
+		progress(0, total)
+	})
+	progress(n, total)
+	return n, err
+}
+
+// StoreFileWithProgress is like StoreFile, but calls progress periodically
+// during the transfer and once with the total number of bytes read from r
+// when the transfer completes.
+func (c *Client) StoreFileWithProgress(ctx context.Context, path string, r io.Reader, progress ProgressFunc) (int64, error) {
+	p := &progressReader{r: r, progressCounter: progressCounter{progress: progress}}
+	n, err := c.StoreFile(ctx, path, p)
+	progress(n)
+	return n, err
+}
+
+// progressCounter counts bytes and throttles calls of a ProgressFunc.
+type progressCounter struct {
+	progress ProgressFunc
+	n        int64
+	last     time.Time
+}
+
+func (p *progressCounter) add(n int) {
+	p.n += int64(n)
+	if now := time.Now(); now.Sub(p.last) >= progressInterval {
+		p.last = now
+		p.progress(p.n)
+	}
+}
+
+type progressWriter struct {
+	w io.Writer
+	progressCounter
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.add(n)
+	return n, err
+}
+
+type progressReader struct {
+	r io.Reader
+	progressCounter
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.add(n)
+	return n, err
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+)
+
+func TestRetrieveFileWithProgress(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	data := make([]byte, 100000)
+	s.setFile("a.bin", data)
+	c := s.dial(ctx)
+
+	var reports []int64
+	n, err := c.RetrieveFileWithProgress(ctx, "a.bin", io.Discard, func(n int64) {
+		reports = append(reports, n)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) == 0 || reports[len(reports)-1] != n || n != int64(len(data)) {
+		t.Errorf("reports = %v (expected last %d)", reports, len(data))
+	}
+	if len(reports) > 3 {
+		t.Errorf("reported %d times (expected throttling)", len(reports))
+	}
+}
+
+func TestStoreFileWithProgress(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	data := make([]byte, 100000)
+	c := s.dial(ctx)
+
+	var last int64
+	n, err := c.StoreFileWithProgress(ctx, "a.bin", bytes.NewReader(data), func(n int64) {
+		last = n
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last != n || n != int64(len(data)) {
+		t.Errorf("last = %d, n = %d (expected %d)", last, n, len(data))
+	}
+}
+
+func TestRetrieveFileWithTotal(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.handle("RETR", func(sc *serverConn, arg string) {
+		sc.opening = "Opening BINARY mode data connection for " + arg + " (13 bytes)"
+		sc.transfer(func(conn io.ReadWriter) error {
+			_, err := io.WriteString(conn, "Hello, World\n")
+			return err
+		})
+	})
+	c := s.dial(ctx)
+
+	var calls [][2]int64
+	n, err := c.RetrieveFileWithTotal(ctx, "hello.txt", io.Discard, func(n, total int64) {
+		calls = append(calls, [2]int64{n, total})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 13 {
+		t.Errorf("n = %d (expected 13)", n)
+	}
+	if len(calls) < 2 || calls[0] != [2]int64{0, 13} || calls[len(calls)-1] != [2]int64{13, 13} {
+		t.Errorf("progress calls = %v", calls)
+	}
+}
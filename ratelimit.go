@@ -0,0 +1,57 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"context"
+	"time"
+)
+
+// rateLimiter is a token bucket limiting throughput to rate bytes per
+// second, with a burst of one second worth of bytes.
+type rateLimiter struct {
+	rate   int64
+	tokens int64
+	last   time.Time
+}
+
+func newRateLimiter(rate int64) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &rateLimiter{rate: rate, tokens: rate, last: time.Now()}
+}
+
+// max returns the number of bytes that may be transferred at once.
+func (l *rateLimiter) max(n int) int {
+	if l != nil && int64(n) > l.rate {
+		return int(l.rate)
+	}
+	return n
+}
+
+// wait takes n tokens from the bucket, waiting until the bucket is no
+// longer in debt or ctx is done.
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	if l == nil {
+		return nil
+	}
+	now := time.Now()
+	l.tokens += int64(now.Sub(l.last)) * l.rate / int64(time.Second)
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+	l.last = now
+	l.tokens -= int64(n)
+	if l.tokens >= 0 {
+		return nil
+	}
+	t := time.NewTimer(time.Duration(-l.tokens * int64(time.Second) / l.rate))
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
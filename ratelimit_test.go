@@ -0,0 +1,59 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	data := make([]byte, 3000)
+	s.setFile("a.bin", data)
+	c := s.dial(ctx)
+	c.DownloadRateLimit = 2000
+	c.UploadRateLimit = 2000
+
+	// The first second worth of bytes is a burst, the rest is throttled.
+	start := time.Now()
+	if _, err := c.RetrieveFile(ctx, "a.bin", io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 400*time.Millisecond {
+		t.Errorf("download took %v (expected at least 500ms)", d)
+	}
+
+	start = time.Now()
+	if _, err := c.StoreFile(ctx, "b.bin", bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 400*time.Millisecond {
+		t.Errorf("upload took %v (expected at least 500ms)", d)
+	}
+	if b, _ := s.file("b.bin"); len(b) != len(data) {
+		t.Errorf("stored %d bytes (expected %d)", len(b), len(data))
+	}
+}
+
+func TestRateLimitCancel(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("a.bin", make([]byte, 100))
+	c := s.dial(ctx)
+	c.DownloadRateLimit = 10
+
+	tctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := c.RetrieveFile(tctx, "a.bin", io.Discard); err != context.DeadlineExceeded {
+		t.Errorf("err = %v (expected %v)", err, context.DeadlineExceeded)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("cancelled download took %v", d)
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"context"
+	"errors"
+)
+
+// Reconnect replaces the control connection, for example after it was
+// dropped, with a new connection to the same server using the options it
+// was dialed with. It then restores the session: the TLS protection set up
+// by AuthTLS or ProtectData, the login, MODE Z and the working directory.
+//
+// Transfers in progress are invalidated; their data connections are closed
+// and closing them returns no error. Reconnect only works for Clients
+// created by Dial or one of its variants.
+func (c *Client) Reconnect(ctx context.Context) error {
+	if c.addr == "" {
+		return errors.New("ftp: client wasn't created by Dial")
+	}
+	nc, err := DialWithOptions(ctx, c.network, c.addr, c.dialOpts)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	if tc := c.transferring; tc != nil {
+		tc.closed = true
+		c.transferring = nil
+		tc.stop()
+		tc.rwc.Close()
+	}
+	c.proto.Close()
+	c.conn, c.proto, c.Welcome = nc.conn, nc.proto, nc.Welcome
+	c.tlsConfig, c.serverHost, c.serverIP = nc.tlsConfig, nc.serverHost, nc.serverIP
+	protected, level := c.dataProtected, c.compressLevel
+	c.dataProtected, c.compressLevel, c.epsvFailed = false, 0, false
+	c.features, c.system, c.dataType = nil, "", ""
+	c.mu.Unlock()
+
+	if c.authConfig != nil {
+		if err := c.AuthTLS(ctx, c.authConfig); err != nil {
+			return err
+		}
+	} else if protected {
+		if err := c.ProtectData(ctx); err != nil {
+			return err
+		}
+	}
+	if c.username != "" {
+		if err := c.Login(ctx, c.username, c.password); err != nil {
+			return err
+		}
+	}
+	if level != 0 {
+		if err := c.SetCompression(ctx, level); err != nil {
+			return err
+		}
+	}
+	if c.dir != "" {
+		return c.simpleCommand(ctx, "CWD "+c.dir)
+	}
+	return nil
+}
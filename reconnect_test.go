@@ -0,0 +1,52 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"context"
+	"io"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestReconnect(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("pub/hello.txt", []byte("Hello, World\n"))
+	var dirs []string
+	s.handle("CWD", func(sc *serverConn, arg string) {
+		dirs = append(dirs, arg)
+		sc.dir = path.Join(sc.dir, arg)
+		sc.reply(250, "Okay")
+	})
+	c := s.dial(ctx)
+
+	if err := c.ChangeDir(ctx, "pub"); err != nil {
+		t.Fatal(err)
+	}
+	c.conn.Close() // drop the connection
+	if _, err := c.RetrieveFile(ctx, "pub/hello.txt", io.Discard); err == nil {
+		t.Fatal("expected error on dropped connection")
+	}
+
+	if err := c.Reconnect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.RetrieveFile(ctx, "pub/hello.txt", io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.count("PASS"); n != 2 {
+		t.Errorf("logged in %d times (expected 2)", n)
+	}
+	if expected := "pub /pub"; strings.Join(dirs, " ") != expected {
+		t.Errorf("CWD %q (expected %q)", dirs, expected)
+	}
+}
+
+func TestReconnectNewClient(t *testing.T) {
+	c := new(Client)
+	if err := c.Reconnect(context.Background()); err == nil {
+		t.Error("expected error")
+	}
+}
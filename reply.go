@@ -4,6 +4,8 @@
 package ftp
 
 import (
+	"errors"
+	"regexp"
 	"strconv"
 	"strings"
 )
@@ -32,6 +34,7 @@ const (
 	CodePassive         Code = 227
 	CodeExtendedPassive Code = 229
 	CodeLoggedIn        Code = 230
+	CodeAuthComplete    Code = 234
 	CodeActionOkay      Code = 250
 	CodeCreated         Code = 257
 
@@ -87,6 +90,11 @@ func (code Code) Temporary() bool {
 	return code/100 == 4
 }
 
+// Permanent returns whether this code indicates a permanent error.
+func (code Code) Permanent() bool {
+	return code/100 == 5
+}
+
 func (code Code) String() string {
 	return strconv.Itoa(int(code))
 }
@@ -97,6 +105,12 @@ type Reply struct {
 	Msg string
 }
 
+// Lines returns the lines of the reply without the reply codes of the
+// first and last line, which Msg holds joined by newlines.
+func (r Reply) Lines() []string {
+	return strings.Split(r.Msg, "\n")
+}
+
 func (r Reply) String() string {
 	lines := strings.Split(r.Msg, "\n")
 	if len(lines) > 1 {
@@ -110,3 +124,45 @@ func (r Reply) String() string {
 func (r Reply) Error() string {
 	return r.String()
 }
+
+var transferSizeRegexp = regexp.MustCompile(`(?i)\b([0-9]+)\s*bytes?\b`)
+
+// TransferSize returns the number of bytes to transfer as announced by many
+// servers in the preliminary reply to RETR, like
+//
+//	150 Opening BINARY mode data connection for hello.txt (13 bytes).
+//
+// It reports false if r isn't a preliminary reply or doesn't mention a size.
+func (r Reply) TransferSize() (int64, bool) {
+	if !r.Preliminary() {
+		return 0, false
+	}
+	// The last match, because the name of the file may match as well.
+	m := transferSizeRegexp.FindAllStringSubmatch(r.Msg, -1)
+	if m == nil {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(m[len(m)-1][1], 10, 64)
+	return n, err == nil
+}
+
+// IsTransient reports whether err is or wraps a Reply with a transient
+// negative completion code (4xx), so the command may succeed when retried.
+func IsTransient(err error) bool {
+	var r Reply
+	return errors.As(err, &r) && r.Temporary()
+}
+
+// IsPermanent reports whether err is or wraps a Reply with a permanent
+// negative completion code (5xx), so retrying the command is pointless.
+func IsPermanent(err error) bool {
+	var r Reply
+	return errors.As(err, &r) && r.Permanent()
+}
+
+// IsFileUnavailable reports whether err is or wraps a Reply indicating that
+// the file is unavailable, for example because it doesn't exist or is busy.
+func IsFileUnavailable(err error) bool {
+	var r Reply
+	return errors.As(err, &r) && (r.Code == CodeFileUnavailable || r.Code == CodeActionNotTaken)
+}
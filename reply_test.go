@@ -0,0 +1,76 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestErrorClassification(t *testing.T) {
+	tests := []struct {
+		Err             error
+		Transient       bool
+		Permanent       bool
+		FileUnavailable bool
+	}{
+		{Reply{Code: CodeActionNotTaken}, true, false, true},
+		{Reply{Code: CodeLocalError}, true, false, false},
+		{Reply{Code: CodeFileUnavailable}, false, true, true},
+		{fmt.Errorf("ftp: %w", Reply{Code: CodeNotImplemented}), false, true, false},
+		{Reply{Code: CodeOkay}, false, false, false},
+		{errors.New("ftp: other"), false, false, false},
+		{nil, false, false, false},
+	}
+	for i, tt := range tests {
+		if got := IsTransient(tt.Err); got != tt.Transient {
+			t.Errorf("tests[%d]: IsTransient expected %t (got %t)", i, tt.Transient, got)
+		}
+		if got := IsPermanent(tt.Err); got != tt.Permanent {
+			t.Errorf("tests[%d]: IsPermanent expected %t (got %t)", i, tt.Permanent, got)
+		}
+		if got := IsFileUnavailable(tt.Err); got != tt.FileUnavailable {
+			t.Errorf("tests[%d]: IsFileUnavailable expected %t (got %t)", i, tt.FileUnavailable, got)
+		}
+	}
+}
+
+func TestTransferSize(t *testing.T) {
+	tests := []struct {
+		Reply Reply
+		Size  int64
+		OK    bool
+	}{
+		{Reply{Code: 150, Msg: "Opening BINARY mode data connection for hello.txt (13 bytes)."}, 13, true},
+		{Reply{Code: 150, Msg: "Opening BINARY mode data connection for hello.txt (13 Bytes)"}, 13, true},
+		{Reply{Code: 125, Msg: "Data connection already open; transfer starting. 1024 bytes"}, 1024, true},
+		{Reply{Code: 150, Msg: "Opening data connection for 2 bytes.txt (5 bytes)"}, 5, true},
+		{Reply{Code: 150, Msg: "Opening BINARY mode data connection."}, 0, false},
+		{Reply{Code: 226, Msg: "Transfer complete (13 bytes)"}, 0, false},
+	}
+	for i, tt := range tests {
+		if size, ok := tt.Reply.TransferSize(); size != tt.Size || ok != tt.OK {
+			t.Errorf("tests[%d]: expected %d, %t (got %d, %t)", i, tt.Size, tt.OK, size, ok)
+		}
+	}
+}
+
+func TestReplyLines(t *testing.T) {
+	tests := []struct {
+		Reply Reply
+		Lines []string
+	}{
+		{Reply{200, "Okay"}, []string{"Okay"}},
+		{Reply{211, "Features:\n MDTM\nEnd"}, []string{"Features:", " MDTM", "End"}},
+	}
+	for i, tt := range tests {
+		if lines := tt.Reply.Lines(); !reflect.DeepEqual(lines, tt.Lines) {
+			t.Errorf("tests[%d]: expected %q (got %q)", i, tt.Lines, lines)
+		}
+	}
+	if (Reply{200, "Okay"}) != (Reply{200, "Okay"}) {
+		t.Error("Reply isn't comparable")
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// A RetryPolicy decides whether a failed attempt is retried. It is called
+// with the number of attempts made so far, starting at 1, and the error of
+// the last attempt. It returns the delay before the next attempt and
+// whether to make it.
+//
+// A command is only retried as a whole: a transfer is retried while
+// opening the data connection, never after data has been transferred.
+// A reply with CodeServiceNotAvailable means the server is closing the
+// control connection, which can only be resolved by Reconnect.
+type RetryPolicy func(attempt int, err error) (time.Duration, bool)
+
+// ExponentialBackoff returns a RetryPolicy that retries transient negative
+// replies (4xx), except CodeServiceNotAvailable, up to retries times. The
+// delay starts at base and doubles on every attempt, up to maxDelay.
+func ExponentialBackoff(retries int, base, maxDelay time.Duration) RetryPolicy {
+	return func(attempt int, err error) (time.Duration, bool) {
+		var reply Reply
+		if attempt > retries || !errors.As(err, &reply) || !reply.Temporary() || reply.Code == CodeServiceNotAvailable {
+			return 0, false
+		}
+		d := base
+		for i := 1; i < attempt && d < maxDelay; i++ {
+			d *= 2
+		}
+		return min(d, maxDelay), true
+	}
+}
+
+// retry calls fn until it succeeds or c.Retry gives up. It gives up early
+// if ctx is done or its deadline would pass before the next attempt,
+// returning the error of the last attempt.
+func (c *Client) retry(ctx context.Context, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || c.Retry == nil {
+			return err
+		}
+		d, ok := c.Retry(attempt, err)
+		if !ok {
+			return err
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+			return err
+		}
+		t := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	policy := ExponentialBackoff(3, 10*time.Millisecond, 25*time.Millisecond)
+	tests := []struct {
+		Attempt int
+		Err     error
+		Delay   time.Duration
+		Retry   bool
+	}{
+		{1, Reply{Code: CodeCantOpenData}, 10 * time.Millisecond, true},
+		{2, Reply{Code: CodeCantOpenData}, 20 * time.Millisecond, true},
+		{3, Reply{Code: CodeCantOpenData}, 25 * time.Millisecond, true},
+		{4, Reply{Code: CodeCantOpenData}, 0, false},
+		{1, Reply{Code: CodeServiceNotAvailable}, 0, false},
+		{1, Reply{Code: CodeFileUnavailable}, 0, false},
+		{1, errors.New("network failure"), 0, false},
+	}
+	for i, tt := range tests {
+		d, ok := policy(tt.Attempt, tt.Err)
+		if d != tt.Delay || ok != tt.Retry {
+			t.Errorf("tests[%d]: expected %v, %t (got %v, %t)", i, tt.Delay, tt.Retry, d, ok)
+		}
+	}
+}
+
+func TestRetryTransfer(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("a.txt", []byte("Hello, World\n"))
+	// failOnce fails RETR once, then restores the default handling.
+	failOnce := func(sc *serverConn, arg string) {
+		sc.s.mu.Lock()
+		delete(sc.s.handlers, "RETR")
+		sc.s.mu.Unlock()
+		sc.reply(425, "Can't open data connection")
+	}
+	s.handle("RETR", failOnce)
+	c := s.dial(ctx)
+
+	if _, err := c.RetrieveFile(ctx, "a.txt", io.Discard); !IsTransient(err) {
+		t.Fatalf("err = %v (expected 425 reply)", err)
+	}
+	s.handle("RETR", failOnce)
+	c.Retry = ExponentialBackoff(1, time.Millisecond, time.Millisecond)
+	if n, err := c.RetrieveFile(ctx, "a.txt", io.Discard); err != nil || n != 13 {
+		t.Errorf("RetrieveFile = %d, %v (expected 13, nil)", n, err)
+	}
+	if n := s.count("RETR"); n != 3 {
+		t.Errorf("RETR sent %d times (expected 3)", n)
+	}
+}
+
+func TestRetryTransferSetup(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("a.txt", []byte("Hello, World\n"))
+	s.handle("TYPE", func(sc *serverConn, arg string) {
+		sc.reply(450, "Try again")
+	})
+	c := s.dial(ctx)
+
+	// The transfer is retried as a whole, so TYPE isn't retried on its own
+	// and one retry means two attempts in total.
+	var attempts int
+	c.Retry = func(attempt int, err error) (time.Duration, bool) {
+		attempts++
+		return time.Millisecond, attempt < 2
+	}
+	if _, err := c.RetrieveFile(ctx, "a.txt", io.Discard); !IsTransient(err) {
+		t.Errorf("err = %v (expected 450 reply)", err)
+	}
+	if attempts != 2 {
+		t.Errorf("Retry called %d times (expected 2)", attempts)
+	}
+	if n := s.count("TYPE"); n != 2 {
+		t.Errorf("TYPE sent %d times (expected 2)", n)
+	}
+}
+
+func TestRetryDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	s := newTestServer(t)
+	s.handle("DELE", func(sc *serverConn, arg string) {
+		sc.reply(450, "File busy")
+	})
+	c := s.dial(ctx)
+	c.Retry = ExponentialBackoff(10, time.Hour, time.Hour)
+
+	start := time.Now()
+	if err := c.Delete(ctx, "a.txt"); !IsTransient(err) {
+		t.Errorf("err = %v (expected 450 reply)", err)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("Delete returned after %v", d)
+	}
+}
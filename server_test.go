@@ -0,0 +1,565 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"compress/zlib"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testServer is a minimal in-memory FTP server used to exercise the client.
+type testServer struct {
+	t     *testing.T
+	ln    net.Listener
+	mu    sync.Mutex
+	files map[string][]byte
+
+	// features are advertised in the FEAT reply.
+	features []string
+
+	// tlsConfig enables AUTH TLS if set.
+	tlsConfig *tls.Config
+	// implicitTLS starts TLS as soon as a client connects.
+	implicitTLS bool
+	// resumed records whether the last protected data connection
+	// resumed a TLS session.
+	resumed bool
+
+	// commands records the verbs received by the server.
+	commands []string
+
+	// handlers override the default command handling by verb.
+	handlers map[string]func(sc *serverConn, arg string)
+}
+
+// serverConn is a single control connection to the testServer.
+type serverConn struct {
+	s     *testServer
+	proto *textproto.Conn
+	conn  net.Conn
+	pasv  net.Listener
+
+	renameFrom string
+	dir        string
+	prot       bool
+	active     string // address to connect to after PORT
+	rest       int64
+	deflate    bool   // MODE Z
+	opening    string // message of the 150 reply of the next transfer
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testServer{
+		t:        t,
+		ln:       ln,
+		files:    make(map[string][]byte),
+		features: []string{"MDTM", "MFMT", "MLST type*;size*;modify*;", "SIZE", "UTF8"},
+		handlers: make(map[string]func(*serverConn, string)),
+	}
+	t.Cleanup(func() { ln.Close() })
+	go s.serve()
+	return s
+}
+
+// dial connects and logs in to the server.
+func (s *testServer) dial(ctx context.Context) *Client {
+	s.t.Helper()
+	c, err := Dial(ctx, "tcp", s.ln.Addr().String())
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	if err := c.Login(ctx, "user", "pass"); err != nil {
+		s.t.Fatal(err)
+	}
+	s.t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func (s *testServer) handle(verb string, fn func(sc *serverConn, arg string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[verb] = fn
+}
+
+func (s *testServer) file(name string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.files[name]
+	return b, ok
+}
+
+func (s *testServer) setFile(name string, b []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[name] = b
+}
+
+func (s *testServer) setFeatures(features ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.features = features
+}
+
+func (s *testServer) setTLS(config *tls.Config, implicit bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tlsConfig = config
+	s.implicitTLS = implicit
+}
+
+func (s *testServer) tlsSettings() (*tls.Config, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tlsConfig, s.implicitTLS
+}
+
+// count returns the number of times verb was received.
+func (s *testServer) count(verb string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, v := range s.commands {
+		if v == verb {
+			n++
+		}
+	}
+	return n
+}
+
+// cleanPath returns name relative to the root without leading slash.
+func cleanPath(name string) string {
+	name = strings.Trim(path.Clean("/"+name), "/")
+	return name
+}
+
+// list returns MLSD lines for the directory dir, or false if it doesn't exist.
+func (s *testServer) list(dir string) ([]string, bool) {
+	dir = cleanPath(dir)
+	prefix := ""
+	if dir != "" {
+		prefix = dir + "/"
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	found := dir == ""
+	seen := make(map[string]bool)
+	var files, dirs []string
+	for name, b := range s.files {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		found = true
+		rest := name[len(prefix):]
+		if i := strings.IndexByte(rest, '/'); i >= 0 {
+			if !seen[rest[:i]] {
+				seen[rest[:i]] = true
+				dirs = append(dirs, "type=dir; "+rest[:i])
+			}
+			continue
+		}
+		files = append(files, fmt.Sprintf("type=file;size=%d; %s", len(b), rest))
+	}
+	if !found {
+		return nil, false
+	}
+	sort.Strings(dirs)
+	sort.Strings(files)
+	lines := []string{"type=cdir; /" + dir, "type=pdir; /" + path.Dir("/"+dir)}
+	return append(append(lines, dirs...), files...), true
+}
+
+func (s *testServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		if config, implicit := s.tlsSettings(); implicit {
+			conn = tls.Server(conn, config)
+		}
+		sc := &serverConn{s: s, proto: textproto.NewConn(conn), conn: conn, dir: "/"}
+		go sc.serve()
+	}
+}
+
+func (sc *serverConn) reply(code int, format string, args ...interface{}) {
+	sc.proto.PrintfLine("%d %s", code, fmt.Sprintf(format, args...))
+}
+
+func (sc *serverConn) serve() {
+	defer sc.proto.Close()
+	defer func() {
+		if sc.pasv != nil {
+			sc.pasv.Close()
+		}
+	}()
+	sc.reply(220, "Service ready")
+	for {
+		line, err := sc.proto.ReadLine()
+		if err != nil {
+			return
+		}
+		line = strings.TrimLeft(line, "\xff\xf4\xf2") // Telnet IP and Synch
+		verb, arg := line, ""
+		if i := strings.IndexByte(line, ' '); i >= 0 {
+			verb, arg = line[:i], line[i+1:]
+		}
+		verb = strings.ToUpper(verb)
+		sc.s.mu.Lock()
+		sc.s.commands = append(sc.s.commands, verb)
+		sc.s.mu.Unlock()
+		sc.s.mu.Lock()
+		fn, ok := sc.s.handlers[verb]
+		sc.s.mu.Unlock()
+		if ok {
+			fn(sc, arg)
+			continue
+		}
+		switch verb {
+		case "USER":
+			sc.reply(331, "Need password")
+		case "PASS":
+			sc.reply(230, "Logged in")
+		case "TYPE", "NOOP":
+			sc.reply(200, "Okay")
+		case "PASV":
+			sc.listenPassive()
+			addr := sc.pasv.Addr().(*net.TCPAddr)
+			ip := addr.IP.To4()
+			sc.reply(227, "Entering Passive Mode (%d,%d,%d,%d,%d,%d)",
+				ip[0], ip[1], ip[2], ip[3], addr.Port>>8, addr.Port&0xff)
+		case "EPSV":
+			sc.listenPassive()
+			sc.reply(229, "Entering Extended Passive Mode (|||%d|)", sc.pasv.Addr().(*net.TCPAddr).Port)
+		case "PORT":
+			addr, err := parsePasvReply(arg)
+			if err != nil {
+				sc.reply(501, "Syntax error")
+				continue
+			}
+			sc.active = addr.String()
+			sc.reply(200, "Okay")
+		case "EPRT":
+			fields := strings.Split(arg, "|")
+			if len(fields) != 5 {
+				sc.reply(501, "Syntax error")
+				continue
+			}
+			sc.active = net.JoinHostPort(fields[2], fields[3])
+			sc.reply(200, "Okay")
+		case "REST":
+			n, err := strconv.ParseInt(arg, 10, 64)
+			if err != nil || n < 0 {
+				sc.reply(501, "Syntax error")
+				continue
+			}
+			sc.rest = n
+			sc.reply(350, "Restarting at %d", n)
+		case "RETR":
+			b, ok := sc.s.file(arg)
+			if !ok {
+				sc.reply(550, "File not found")
+				continue
+			}
+			if sc.rest > int64(len(b)) {
+				sc.reply(554, "Invalid restart offset")
+				continue
+			}
+			b, sc.rest = b[sc.rest:], 0
+			sc.transfer(func(conn io.ReadWriter) error {
+				_, err := conn.Write(b)
+				return err
+			})
+		case "STOR", "APPE":
+			old, _ := sc.s.file(arg)
+			switch {
+			case verb == "APPE":
+			case sc.rest > int64(len(old)):
+				sc.reply(554, "Invalid restart offset")
+				continue
+			default:
+				old = old[:sc.rest]
+			}
+			sc.rest = 0
+			sc.transfer(func(conn io.ReadWriter) error {
+				b, err := io.ReadAll(conn)
+				if err == nil {
+					sc.s.setFile(arg, append(old[:len(old):len(old)], b...))
+				}
+				return err
+			})
+		case "NLST":
+			lines, ok := sc.s.list(arg)
+			if !ok {
+				sc.reply(550, "Directory not found")
+				continue
+			}
+			var names []string
+			for _, line := range lines[2:] {
+				names = append(names, line[strings.Index(line, "; ")+2:])
+			}
+			sc.transfer(func(conn io.ReadWriter) error {
+				for _, name := range names {
+					if _, err := io.WriteString(conn, name+"\r\n"); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		case "DELE":
+			sc.s.mu.Lock()
+			_, ok := sc.s.files[arg]
+			delete(sc.s.files, arg)
+			sc.s.mu.Unlock()
+			if !ok {
+				sc.reply(550, "File not found")
+				continue
+			}
+			sc.reply(250, "Deleted")
+		case "RMD":
+			if lines, ok := sc.s.list(arg); ok && len(lines) > 2 {
+				sc.reply(550, "Directory not empty")
+				continue
+			}
+			sc.reply(250, "Directory removed")
+		case "RNFR":
+			if _, ok := sc.s.file(arg); !ok {
+				sc.reply(550, "File not found")
+				continue
+			}
+			sc.renameFrom = arg
+			sc.reply(350, "Ready for RNTO")
+		case "RNTO":
+			if sc.renameFrom == "" {
+				sc.reply(503, "Bad sequence of commands")
+				continue
+			}
+			sc.s.mu.Lock()
+			sc.s.files[arg] = sc.s.files[sc.renameFrom]
+			delete(sc.s.files, sc.renameFrom)
+			sc.s.mu.Unlock()
+			sc.renameFrom = ""
+			sc.reply(250, "Renamed")
+		case "MKD":
+			if _, ok := sc.s.list(arg); ok {
+				sc.reply(550, "Directory already exists")
+				continue
+			}
+			sc.reply(257, "\"/%s\" created", cleanPath(arg))
+		case "CWD":
+			sc.dir = path.Join(sc.dir, arg)
+			sc.reply(250, "Okay")
+		case "CDUP":
+			sc.dir = path.Dir(sc.dir)
+			sc.reply(250, "Okay")
+		case "PWD":
+			sc.reply(257, "%q is the current directory", sc.dir)
+		case "SIZE":
+			b, ok := sc.s.file(arg)
+			if !ok {
+				sc.reply(550, "File not found")
+				continue
+			}
+			sc.reply(213, "%d", len(b))
+		case "MDTM":
+			if _, ok := sc.s.file(arg); !ok {
+				sc.reply(550, "File not found")
+				continue
+			}
+			sc.reply(213, "20200102030405.5")
+		case "MFMT":
+			fields := strings.SplitN(arg, " ", 2)
+			if len(fields) != 2 {
+				sc.reply(501, "Syntax error")
+				continue
+			}
+			if _, ok := sc.s.file(fields[1]); !ok {
+				sc.reply(550, "File not found")
+				continue
+			}
+			sc.reply(213, "Modify=%s; %s", fields[0], fields[1])
+		case "AUTH":
+			config, _ := sc.s.tlsSettings()
+			if config == nil || !strings.EqualFold(arg, "TLS") {
+				sc.reply(504, "Security mechanism not understood")
+				continue
+			}
+			sc.reply(234, "AUTH TLS successful")
+			sc.conn = tls.Server(sc.conn, config)
+			sc.proto = textproto.NewConn(sc.conn)
+		case "PBSZ":
+			sc.reply(200, "PBSZ=0")
+		case "PROT":
+			sc.prot = arg == "P"
+			sc.reply(200, "Okay")
+		case "MLSD":
+			lines, ok := sc.s.list(arg)
+			if !ok {
+				sc.reply(550, "Directory not found")
+				continue
+			}
+			sc.transfer(func(conn io.ReadWriter) error {
+				for _, line := range lines {
+					if _, err := io.WriteString(conn, line+"\r\n"); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		case "LIST":
+			lines, ok := sc.s.list(arg)
+			if !ok {
+				sc.reply(550, "Directory not found")
+				continue
+			}
+			sc.transfer(func(conn io.ReadWriter) error {
+				for _, line := range lines[2:] {
+					var err error
+					name := line[strings.Index(line, "; ")+2:]
+					if strings.HasPrefix(line, "type=dir;") {
+						_, err = fmt.Fprintf(conn, "01-02-20  03:04PM       <DIR>          %s\r\n", name)
+					} else {
+						_, err = fmt.Fprintf(conn, "-rw-r--r--   1 owner    group           0 Jan 02  2020 %s\r\n", name)
+					}
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		case "FEAT":
+			sc.s.mu.Lock()
+			features := sc.s.features
+			sc.s.mu.Unlock()
+			sc.proto.PrintfLine("211-Features:")
+			for _, feat := range features {
+				sc.proto.PrintfLine(" %s", feat)
+			}
+			sc.reply(211, "End")
+		case "MLST":
+			b, ok := sc.s.file(arg)
+			if !ok {
+				sc.reply(550, "File not found")
+				continue
+			}
+			sc.proto.PrintfLine("250-Listing %s", arg)
+			sc.proto.PrintfLine(" type=file;size=%d; %s", len(b), arg)
+			sc.reply(250, "End")
+		case "MODE":
+			switch strings.ToUpper(arg) {
+			case "S":
+				sc.deflate = false
+			case "Z":
+				sc.deflate = true
+			default:
+				sc.reply(504, "Mode not supported")
+				continue
+			}
+			sc.reply(200, "Okay")
+		case "OPTS":
+			if strings.HasPrefix(strings.ToUpper(arg), "MODE Z ") {
+				sc.reply(200, "Okay")
+				continue
+			}
+			if !strings.EqualFold(arg, "UTF8 ON") {
+				sc.reply(501, "Option not understood")
+				continue
+			}
+			sc.reply(200, "UTF8 set to on")
+		case "SYST":
+			sc.reply(215, "UNIX Type: L8")
+		case "ABOR":
+			sc.reply(226, "Abort successful")
+		case "REIN":
+			sc.prot, sc.deflate, sc.dir = false, false, "/"
+			sc.reply(220, "Service ready")
+		case "QUIT":
+			sc.reply(221, "Bye")
+			return
+		default:
+			sc.reply(502, "Command not implemented")
+		}
+	}
+}
+
+func (sc *serverConn) listenPassive() {
+	if sc.pasv != nil {
+		sc.pasv.Close()
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		sc.s.t.Error(err)
+		return
+	}
+	sc.pasv = ln
+}
+
+// transfer opens the data connection and runs fn over it.
+func (sc *serverConn) transfer(fn func(conn io.ReadWriter) error) {
+	var (
+		conn net.Conn
+		err  error
+	)
+	opening := "Opening data connection"
+	if sc.opening != "" {
+		opening, sc.opening = sc.opening, ""
+	}
+	switch {
+	case sc.active != "":
+		sc.reply(150, "%s", opening)
+		conn, err = net.Dial("tcp", sc.active)
+		sc.active = ""
+	case sc.pasv != nil:
+		sc.reply(150, "%s", opening)
+		conn, err = sc.pasv.Accept()
+		sc.pasv.Close()
+		sc.pasv = nil
+	default:
+		sc.reply(425, "Use PORT or PASV first")
+		return
+	}
+	if err != nil {
+		sc.reply(425, "Can't open data connection")
+		return
+	}
+	if sc.prot {
+		config, _ := sc.s.tlsSettings()
+		conn = tls.Server(conn, config)
+	}
+	if sc.deflate {
+		z := &zlibConn{rwc: conn, level: zlib.DefaultCompression}
+		err = fn(z)
+		if cerr := z.Close(); err == nil {
+			err = cerr
+		}
+	} else {
+		err = fn(conn)
+	}
+	if tc, ok := conn.(*tls.Conn); ok {
+		sc.s.mu.Lock()
+		sc.s.resumed = tc.ConnectionState().DidResume
+		sc.s.mu.Unlock()
+	}
+	conn.Close()
+	if err != nil {
+		sc.reply(426, "Transfer aborted")
+		return
+	}
+	sc.reply(226, "Transfer complete")
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/textproto"
+)
+
+// DialTLS connects to an FTP server using implicit FTPS, which is usually
+// served on port 990. The TLS handshake is performed before the welcome
+// message is read. If config doesn't set ServerName, it is derived from addr.
+// Call ProtectData to also protect the data connections.
+func DialTLS(ctx context.Context, network, addr string, config *tls.Config) (*Client, error) {
+	if config == nil {
+		config = new(tls.Config)
+	}
+	return DialWithOptions(ctx, network, addr, DialOptions{TLSConfig: config})
+}
+
+// DialAndLoginTLS connects to the FTP server at addr using explicit FTPS
+// and logs in. It secures the control connection with AUTH TLS, enables
+// protection of the data connections with PBSZ and PROT P, and then sends
+// the credentials, so they are never sent in plain text. If config doesn't
+// set ServerName, the host of addr is used.
+func DialAndLoginTLS(ctx context.Context, addr, username, password string, config *tls.Config) (*Client, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	c, err := Dial(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.AuthTLS(ctx, sessionConfig(config, host)); err != nil {
+		c.Close()
+		return nil, err
+	}
+	if err := c.Login(ctx, username, password); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// AuthTLS upgrades the control connection to TLS using AUTH TLS as defined
+// in RFC 4217 and enables protection of the data connections with PBSZ and
+// PROT P. It must be called before Login. If config doesn't set ServerName,
+// the host of the remote address, or the host dialed through a proxy,
+// is used.
+func (c *Client) AuthTLS(ctx context.Context, config *tls.Config) error {
+	reply, err := c.sendCommand(ctx, "AUTH TLS")
+	if err != nil {
+		return err
+	} else if reply.Code != CodeAuthComplete {
+		return reply
+	}
+
+	host := c.serverHost
+	if host == "" {
+		host, _, err = net.SplitHostPort(c.conn.RemoteAddr().String())
+		if err != nil {
+			return err
+		}
+	}
+	config = sessionConfig(config, host)
+	conn := tls.Client(c.conn, config)
+	if err := conn.HandshakeContext(ctx); err != nil {
+		return err
+	}
+	c.conn = conn
+	c.proto = textproto.NewConn(conn)
+	c.tlsConfig = config
+	c.authConfig = config
+	c.features = nil // servers may advertise more features after AUTH
+	return c.ProtectData(ctx)
+}
+
+// ProtectData enables protection of the data connections using PBSZ and
+// PROT P as defined in RFC 4217. The control connection must already be
+// secured using AuthTLS or DialTLS.
+func (c *Client) ProtectData(ctx context.Context) error {
+	if c.tlsConfig == nil {
+		return errors.New("ftp: control connection is not secured")
+	}
+	if err := c.simpleCommand(ctx, "PBSZ 0"); err != nil {
+		return err
+	}
+	if err := c.simpleCommand(ctx, "PROT P"); err != nil {
+		return err
+	}
+	c.dataProtected = true
+	return nil
+}
+
+// protectData wraps a data connection in TLS if data protection is active.
+// The handshake is deferred until the first read or write, because servers
+// only start the handshake after the transfer command has been accepted.
+func (c *Client) protectData(conn net.Conn) net.Conn {
+	if !c.dataProtected {
+		return conn
+	}
+	config := c.tlsConfig
+	if c.DisableTLSSessionReuse {
+		config = config.Clone()
+		config.ClientSessionCache = nil
+	}
+	return tls.Client(conn, config)
+}
+
+// sessionConfig returns a copy of config that allows the TLS session of the
+// control connection to be resumed by the data connections. Some servers
+// require this to prove the data connection belongs to the same client.
+// Resumption only works if both connections use the same ServerName and
+// session cache, so ServerName defaults to host.
+func sessionConfig(config *tls.Config, host string) *tls.Config {
+	if config == nil {
+		config = new(tls.Config)
+	} else {
+		config = config.Clone()
+	}
+	if config.ServerName == "" {
+		config.ServerName = host
+	}
+	if config.ClientSessionCache == nil {
+		config.ClientSessionCache = tls.NewLRUClientSessionCache(0)
+	}
+	return config
+}
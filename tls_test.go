@@ -0,0 +1,184 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+// testTLSConfigs returns a server and client TLS configuration
+// sharing a self-signed certificate for 127.0.0.1.
+func testTLSConfigs(t *testing.T) (server, client *tls.Config) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ftp.example.com"},
+		DNSNames:              []string{"ftp.example.com"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	server = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	}
+	client = &tls.Config{
+		ServerName: "ftp.example.com",
+		RootCAs:    pool,
+	}
+	return server, client
+}
+
+func TestAuthTLS(t *testing.T) {
+	const expected = "Hello, World\n"
+
+	ctx := context.Background()
+	s := newTestServer(t)
+	serverConfig, clientConfig := testTLSConfigs(t)
+	s.setTLS(serverConfig, false)
+	s.setFile("hello.txt", []byte(expected))
+
+	c, err := Dial(ctx, "tcp", s.ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.AuthTLS(ctx, clientConfig); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Login(ctx, "user", "pass"); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := c.RetrieveFile(ctx, "hello.txt", &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != expected {
+		t.Errorf("data = %q (expected %q)", buf.String(), expected)
+	}
+}
+
+func TestDialAndLoginTLS(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	serverConfig, clientConfig := testTLSConfigs(t)
+	clientConfig.ServerName = "" // use the IP address of addr
+	s.setTLS(serverConfig, false)
+	s.setFile("hello.txt", []byte("Hello, World\n"))
+
+	c, err := DialAndLoginTLS(ctx, s.ln.Addr().String(), "user", "pass", clientConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if !c.dataProtected {
+		t.Error("data connections not protected")
+	}
+	if _, err := c.RetrieveFile(ctx, "hello.txt", io.Discard); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTLSSessionReuse(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	serverConfig, clientConfig := testTLSConfigs(t)
+	s.setTLS(serverConfig, false)
+	s.setFile("hello.txt", []byte("Hello, World\n"))
+
+	c, err := Dial(ctx, "tcp", s.ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.AuthTLS(ctx, clientConfig); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Login(ctx, "user", "pass"); err != nil {
+		t.Fatal(err)
+	}
+
+	resumed := func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.resumed
+	}
+	if _, err := c.RetrieveFile(ctx, "hello.txt", io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if !resumed() {
+		t.Error("data connection didn't resume the TLS session")
+	}
+
+	c.DisableTLSSessionReuse = true
+	if _, err := c.RetrieveFile(ctx, "hello.txt", io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if resumed() {
+		t.Error("data connection resumed the TLS session")
+	}
+}
+
+func TestDialTLS(t *testing.T) {
+	const expected = "Hello, World\n"
+
+	ctx := context.Background()
+	serverConfig, clientConfig := testTLSConfigs(t)
+	s := newTestServer(t)
+	s.setTLS(serverConfig, true)
+	s.setFile("hello.txt", []byte(expected))
+
+	c, err := DialTLS(ctx, "tcp", s.ln.Addr().String(), clientConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if c.Welcome.Code != CodeServiceReady {
+		t.Errorf("Welcome = %v", c.Welcome)
+	}
+	if err := c.Login(ctx, "user", "pass"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ProtectData(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := c.RetrieveFile(ctx, "hello.txt", &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != expected {
+		t.Errorf("data = %q (expected %q)", buf.String(), expected)
+	}
+}
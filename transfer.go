@@ -5,26 +5,112 @@ package ftp
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
-// Text sends a command and opens a new passive data connection in ASCII mode.
+// ErrTransferInProgress is returned when a transfer is started while
+// another transfer on the same Client is being opened or is in progress.
+var ErrTransferInProgress = errors.New("ftp: transfer in progress")
+
+// ErrRestartRejected is returned when the server rejects restarting
+// a transfer at an offset.
+var ErrRestartRejected = errors.New("ftp: restart rejected by server")
+
+// Text sends a command and opens a new data connection in ASCII mode.
+// Line endings are translated: LF written to the connection is sent as
+// CRLF and CRLF received from the server is read as LF.
 func (c *Client) Text(ctx context.Context, command string) (Reply, io.ReadWriteCloser, error) {
-	return c.transfer(ctx, command, "A")
+	return c.Transfer(ctx, command, "A")
 }
 
-// Binary sends a command and opens a new passive data connection in image mode.
+// Binary sends a command and opens a new data connection in image mode.
 func (c *Client) Binary(ctx context.Context, command string) (Reply, io.ReadWriteCloser, error) {
-	return c.transfer(ctx, command, "I")
+	return c.Transfer(ctx, command, "I")
+}
+
+// Transfer sends a command and opens a new data connection using the
+// representation type typeCode, as sent with TYPE: "A" for ASCII, "E" for
+// EBCDIC, each optionally followed by a format control "N", "T" or "C",
+// "I" for image or "L" followed by a byte size, like "L 8". Line endings
+// are translated for ASCII like in Text; other types are passed through.
+// An invalid typeCode is rejected without sending anything.
+func (c *Client) Transfer(ctx context.Context, command, typeCode string) (Reply, io.ReadWriteCloser, error) {
+	typeCode = strings.ToUpper(typeCode)
+	if !validType(typeCode) {
+		return Reply{}, nil, fmt.Errorf("ftp: invalid type code %q", typeCode)
+	}
+	reply, conn, err := c.transfer(ctx, command, typeCode, 0)
+	if err != nil {
+		return reply, nil, err
+	}
+	if typeCode[0] == 'A' {
+		conn = newASCIIConn(conn)
+	}
+	return reply, conn, nil
 }
 
-// transfer sends a command and opens a new passive data connection.
-func (c *Client) transfer(ctx context.Context, command, dataType string) (Reply, io.ReadWriteCloser, error) {
+// validType reports whether typeCode is a valid argument to TYPE as
+// defined in RFC 959.
+func validType(typeCode string) bool {
+	code, param, _ := strings.Cut(typeCode, " ")
+	switch code {
+	case "A", "E":
+		return param == "" || param == "N" || param == "T" || param == "C"
+	case "I":
+		return typeCode == "I"
+	case "L":
+		n, err := strconv.Atoi(param)
+		return err == nil && n > 0 && n < 256 && param[0] != '+'
+	}
+	return false
+}
+
+// transfer sends a command and opens a new data connection.
+// If offset is non-zero, the transfer is restarted at offset using REST.
+// Failures are retried according to c.Retry, opening a new data connection
+// on each attempt.
+func (c *Client) transfer(ctx context.Context, command, dataType string, offset int64) (reply Reply, conn io.ReadWriteCloser, err error) {
+	err = c.retry(ctx, func() (err error) {
+		reply, conn, err = c.transferOnce(ctx, command, dataType, offset)
+		return err
+	})
+	return reply, conn, err
+}
+
+// transferOnce makes a single attempt of transfer.
+func (c *Client) transferOnce(ctx context.Context, command, dataType string, offset int64) (Reply, io.ReadWriteCloser, error) {
+	c.mu.Lock()
+	if c.transferring != nil || c.opening {
+		c.mu.Unlock()
+		return Reply{}, nil, ErrTransferInProgress
+	}
+	c.opening = true
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.opening = false
+		c.mu.Unlock()
+	}()
+
 	// Set type
-	if reply, err := c.sendCommand(ctx, "TYPE "+dataType); err != nil {
+	if err := c.setType(ctx, dataType); err != nil {
+		return Reply{}, nil, err
+	}
+
+	if c.ActiveMode {
+		return c.transferActive(ctx, command, offset)
+	}
+
+	// Announce the transfer to distributed servers
+	if err := c.preTransfer(ctx, command); err != nil {
 		return Reply{}, nil, err
-	} else if !reply.PositiveComplete() {
-		return Reply{}, nil, reply
 	}
 
 	// Open data connection
@@ -39,53 +125,350 @@ func (c *Client) transfer(ctx context.Context, command, dataType string) (Reply,
 	}(conn)
 
 	// Send command
-	reply, err := c.sendCommand(ctx, command)
+	reply, err := c.startTransfer(ctx, command, offset)
 	if err != nil {
 		return Reply{}, nil, err
+	}
+	return reply, c.newTransferConn(ctx, conn), nil
+}
+
+// transferActive sends a command and accepts a new active data connection.
+func (c *Client) transferActive(ctx context.Context, command string, offset int64) (Reply, io.ReadWriteCloser, error) {
+	// Listen for data connection
+	ln, err := c.openActive(ctx)
+	if err != nil {
+		return Reply{}, nil, err
+	}
+	defer ln.Close()
+
+	// Send command
+	reply, err := c.startTransfer(ctx, command, offset)
+	if err != nil {
+		return Reply{}, nil, err
+	}
+
+	// Accept data connection
+	conn, err := c.acceptActive(ctx, ln)
+	if err != nil {
+		// The transfer has started, so abort it to read its pending reply
+		// and keep the control connection in sync.
+		c.mu.Lock()
+		c.abortWithTimeout()
+		c.mu.Unlock()
+		return Reply{}, nil, err
+	}
+	return reply, c.newTransferConn(ctx, conn), nil
+}
+
+// preTransfer sends PRET with command if UsePRET is set or the server
+// advertises PRET.
+func (c *Client) preTransfer(ctx context.Context, command string) error {
+	use := c.UsePRET
+	if !use {
+		var err error
+		if use, err = c.supports(ctx, "PRET"); err != nil {
+			return err
+		}
+	}
+	if !use {
+		return nil
+	}
+	return c.simpleCommandOnce(ctx, "PRET "+command)
+}
+
+// A DataAddr is the address of a data connection and the command used to
+// negotiate it: EPSV or PASV for passive connections dialed by the client,
+// and EPRT or PORT for active connections accepted by the client. Command
+// is empty if PassiveAddrFunc returned the address without negotiating it.
+type DataAddr struct {
+	net.TCPAddr
+	Command string
+}
+
+// LastDataAddr returns the address of the last data connection, or nil if
+// none was negotiated yet. The address is recorded before the connection is
+// made, so it helps to diagnose data connections that fail, for example
+// because the server advertises an unreachable address behind NAT.
+func (c *Client) LastDataAddr() *DataAddr {
+	return c.lastDataAddr
+}
+
+const (
+	// DefaultBufferSize is the size of the buffer used to copy data
+	// transfers, unless changed by SetBufferSize.
+	DefaultBufferSize = 32 << 10
+
+	// MinBufferSize is the smallest buffer size accepted by SetBufferSize.
+	MinBufferSize = 4 << 10
+)
+
+// SetBufferSize sets the size in bytes of the buffer used by the retrieve
+// and store methods to copy data transfers. Larger buffers need fewer
+// system calls, which improves throughput on fast links. Sizes below
+// MinBufferSize are raised to it and zero restores DefaultBufferSize.
+func (c *Client) SetBufferSize(n int) {
+	if n != 0 {
+		n = max(n, MinBufferSize)
+	}
+	c.bufferSize = n
+}
+
+// copy copies from src to dst like io.Copy, using a buffer of the size set
+// by SetBufferSize. The buffer is used even if dst implements
+// io.ReaderFrom or src implements io.WriterTo, because those would copy
+// using their own buffer.
+func (c *Client) copy(dst io.Writer, src io.Reader) (int64, error) {
+	size := c.bufferSize
+	if size == 0 {
+		size = DefaultBufferSize
+	}
+	return io.CopyBuffer(struct{ io.Writer }{dst}, struct{ io.Reader }{src}, make([]byte, size))
+}
+
+// startTransfer sends the transfer command,
+// preceded by REST if offset is non-zero.
+func (c *Client) startTransfer(ctx context.Context, command string, offset int64) (Reply, error) {
+	if offset != 0 {
+		reply, err := c.sendCommand(ctx, "REST "+strconv.FormatInt(offset, 10))
+		if err != nil {
+			return Reply{}, err
+		} else if reply.Code != CodePendingInformation {
+			return Reply{}, fmt.Errorf("%w: %v", ErrRestartRejected, reply)
+		}
+	}
+	reply, err := c.sendCommand(ctx, command)
+	if err != nil {
+		return Reply{}, err
 	} else if !reply.Positive() {
-		return Reply{}, nil, reply
+		return Reply{}, reply
+	}
+	return reply, nil
+}
+
+// setType sets the representation type used for data transfers.
+// TYPE is only sent if the type differs from the last type set.
+func (c *Client) setType(ctx context.Context, dataType string) error {
+	if c.dataType == dataType {
+		return nil
+	}
+	if err := c.simpleCommandOnce(ctx, "TYPE "+dataType); err != nil {
+		return err
 	}
-	return reply, &transferConn{conn, c, ctx}, nil
+	c.dataType = dataType
+	return nil
 }
 
 type transferConn struct {
-	rwc io.ReadWriteCloser
-	c   *Client
-	ctx context.Context
+	rwc    io.ReadWriteCloser
+	conn   net.Conn // underlying data connection of rwc
+	c      *Client
+	ctx    context.Context
+	closed bool
+	stop   func() bool // stops interrupting conn when ctx is done
+
+	rlimit, wlimit *rateLimiter
 }
 
+// newTransferConn wraps a data connection. If ctx has a deadline, it is set
+// on conn, and when ctx is done, a deadline in the past is set, so reads and
+// writes blocked on a stalled connection return promptly.
+func (c *Client) newTransferConn(ctx context.Context, conn net.Conn) *transferConn {
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	tc := &transferConn{
+		rwc:  c.compress(conn),
+		conn: conn,
+		c:    c,
+		ctx:  ctx,
+		stop: context.AfterFunc(ctx, func() {
+			conn.SetDeadline(time.Unix(1, 0))
+		}),
+		rlimit: newRateLimiter(c.DownloadRateLimit),
+		wlimit: newRateLimiter(c.UploadRateLimit),
+	}
+	c.mu.Lock()
+	c.transferring = tc
+	c.mu.Unlock()
+	return tc
+}
+
+// Read reads from the data connection. If the context is done after data
+// was read, the data is returned and the next call returns the error of
+// the context, so no data is lost.
 func (tc *transferConn) Read(p []byte) (n int, err error) {
-	if tc.ctx.Done() == nil {
-		return tc.rwc.Read(p)
+	n, err = tc.read(p[:tc.rlimit.max(len(p))])
+	if werr := tc.rlimit.wait(tc.ctx, n); err == nil {
+		err = werr
 	}
-	select {
-	default:
-		return tc.rwc.Read(p)
-	case <-tc.ctx.Done():
-		return 0, tc.ctx.Err()
+	if n > 0 && err != nil && err == tc.ctx.Err() {
+		err = nil
+	}
+	return n, err
+}
+
+func (tc *transferConn) read(p []byte) (n int, err error) {
+	if err := tc.ctx.Err(); err != nil {
+		return 0, err
 	}
+	n, err = tc.rwc.Read(p)
+	return n, tc.contextErr(err)
 }
 
 func (tc *transferConn) Write(p []byte) (n int, err error) {
-	if tc.ctx.Done() == nil {
-		return tc.rwc.Write(p)
+	for len(p) > 0 {
+		chunk := p[:tc.wlimit.max(len(p))]
+		if err := tc.wlimit.wait(tc.ctx, len(chunk)); err != nil {
+			return n, err
+		}
+		m, err := tc.write(chunk)
+		n += m
+		if err != nil {
+			return n, err
+		}
+		p = p[m:]
 	}
-	select {
-	default:
-		return tc.rwc.Write(p)
-	case <-tc.ctx.Done():
-		return 0, tc.ctx.Err()
+	return n, nil
+}
+
+func (tc *transferConn) write(p []byte) (n int, err error) {
+	if err := tc.ctx.Err(); err != nil {
+		return 0, err
 	}
+	n, err = tc.rwc.Write(p)
+	return n, tc.contextErr(err)
 }
 
+// contextErr returns the error of the context instead of err if the context
+// is done, because the deadline of conn was set to interrupt the operation.
+func (tc *transferConn) contextErr(err error) error {
+	if err == nil {
+		return nil
+	} else if cerr := tc.ctx.Err(); cerr != nil {
+		return cerr
+	} else if errors.Is(err, os.ErrDeadlineExceeded) {
+		// The deadline of the context passed before it was done.
+		return context.DeadlineExceeded
+	}
+	return err
+}
+
+// Close closes the data connection and reads the completion reply.
+// If the context of the transfer is done, the transfer is aborted instead,
+// so the control connection remains usable. If the server doesn't reply
+// to the abort within abortTimeout, the control connection is closed.
 func (tc *transferConn) Close() error {
-	if err := tc.rwc.Close(); err != nil {
-		return err
+	tc.c.mu.Lock()
+	defer tc.c.mu.Unlock()
+	if tc.closed {
+		return nil
 	}
-	if reply, err := tc.c.readResponse(); err != nil {
+	tc.closed = true
+	tc.c.transferring = nil
+	tc.stop()
+	if tc.ctx.Err() != nil {
+		tc.rwc.Close()
+		return tc.c.abortWithTimeout()
+	}
+	// Read the completion reply even if closing fails, for example when
+	// flushing compressed data, to keep the control connection in sync.
+	cerr := tc.rwc.Close()
+	if cerr != nil {
+		tc.conn.Close()
+	}
+	reply, err := tc.c.readResponse()
+	if cerr != nil {
+		return cerr
+	} else if err != nil {
 		return err
 	} else if !reply.PositiveComplete() {
 		return reply
 	}
 	return nil
 }
+
+// abortTimeout limits the time to abort a transfer whose context is done.
+var abortTimeout = 5 * time.Second
+
+// abortWithTimeout aborts a pending transfer, waiting at most abortTimeout
+// for the replies, because the context that would bound the wait is done
+// already. If the server doesn't reply in time, the control connection is
+// closed, because it is out of sync.
+func (c *Client) abortWithTimeout() error {
+	c.conn.SetDeadline(time.Now().Add(abortTimeout))
+	err := c.abort(true)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		c.Close()
+		return err
+	}
+	c.conn.SetDeadline(time.Time{})
+	return err
+}
+
+// Telnet commands sent before ABOR as described in RFC 959 section 4.1.3.
+const (
+	telnetIP = "\xff\xf4" // Interrupt Process
+	telnetDM = "\xff\xf2" // Data Mark, the Synch signal
+)
+
+// Abort aborts the active transfer, if any, using the ABOR command.
+// It closes the data connection and consumes both the reply of the aborted
+// transfer and the reply to ABOR, leaving the control connection usable.
+// The Telnet IP and Synch sequence precedes ABOR, but isn't sent as urgent
+// data, which most servers don't require.
+func (c *Client) Abort(ctx context.Context) error {
+	if ctx.Done() == nil {
+		return c.abortTransfer()
+	}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- c.abortTransfer()
+	}()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Client) abortTransfer() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pending := false
+	if tc := c.transferring; tc != nil {
+		tc.closed = true
+		c.transferring = nil
+		tc.stop()
+		tc.rwc.Close()
+		pending = true
+	}
+	return c.abort(pending)
+}
+
+// abort sends ABOR and reads its reply. If the reply of a transfer is
+// pending, it is read first, whether the transfer was aborted or completed.
+func (c *Client) abort(pending bool) error {
+	if err := c.writeLinePrefix(telnetIP+telnetDM, "ABOR"); err != nil {
+		return err
+	}
+	if pending {
+		if _, err := c.readResponse(); err != nil {
+			return err
+		}
+	}
+	reply, err := c.readResponse()
+	if err != nil {
+		return err
+	}
+	if !pending && (reply.Code == CodeTransferAborted || reply.Code == CodeLocalError) {
+		reply, err = c.readResponse()
+		if err != nil {
+			return err
+		}
+	}
+	if !reply.PositiveComplete() {
+		return reply
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTransferConnDeadline(t *testing.T) {
+	conn, peer := net.Pipe()
+	defer peer.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// The peer never writes, so only the deadline can end the Read.
+	tc := new(Client).newTransferConn(ctx, conn)
+	start := time.Now()
+	if _, err := tc.Read(make([]byte, 1)); err != context.DeadlineExceeded {
+		t.Errorf("Read = %v (expected %v)", err, context.DeadlineExceeded)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Read returned after %v", d)
+	}
+	if _, err := tc.Write(make([]byte, 1)); err != context.DeadlineExceeded {
+		t.Errorf("Write = %v (expected %v)", err, context.DeadlineExceeded)
+	}
+}
+
+func TestTransferConnCancel(t *testing.T) {
+	conn, peer := net.Pipe()
+	defer peer.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tc := new(Client).newTransferConn(ctx, conn)
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	if _, err := tc.Read(make([]byte, 1)); err != context.Canceled {
+		t.Errorf("Read = %v (expected %v)", err, context.Canceled)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Read returned after %v", d)
+	}
+}
+
+func TestTransferConnCancelPartial(t *testing.T) {
+	conn, peer := net.Pipe()
+	defer peer.Close()
+	go peer.Write([]byte("ab"))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The second byte exceeds the rate limit, so its Read waits until
+	// the context is cancelled.
+	c := &Client{DownloadRateLimit: 1}
+	tc := c.newTransferConn(ctx, conn)
+	time.AfterFunc(50*time.Millisecond, cancel)
+	b := make([]byte, 2)
+	for i, expected := range []string{"a", "b"} {
+		n, err := tc.Read(b)
+		if err != nil || string(b[:n]) != expected {
+			t.Errorf("Read %d = %q, %v (expected %q)", i, b[:n], err, expected)
+		}
+	}
+	if n, err := tc.Read(b); n != 0 || err != context.Canceled {
+		t.Errorf("Read = %d, %v (expected 0, %v)", n, err, context.Canceled)
+	}
+}
+
+func TestSetBufferSize(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	data := bytes.Repeat([]byte("0123456789"), 10000)
+	s.setFile("a.bin", data)
+	c := s.dial(ctx)
+
+	for i, size := range []int{1, 1 << 20, 0} {
+		c.SetBufferSize(size)
+		var buf bytes.Buffer
+		if _, err := c.RetrieveFile(ctx, "a.bin", &buf); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), data) {
+			t.Errorf("tests[%d]: retrieved %d bytes (expected %d)", i, buf.Len(), len(data))
+		}
+	}
+	if c.SetBufferSize(1); c.bufferSize != MinBufferSize {
+		t.Errorf("bufferSize = %d (expected %d)", c.bufferSize, MinBufferSize)
+	}
+}
+
+func TestTransferInProgress(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("hello.txt", []byte("Hello, World\n"))
+	c := s.dial(ctx)
+
+	r, err := c.RetrieveReader(ctx, "hello.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.RetrieveFile(ctx, "hello.txt", io.Discard); err != ErrTransferInProgress {
+		t.Errorf("err = %v (expected %v)", err, ErrTransferInProgress)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Concurrent transfers either succeed or are rejected.
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = c.RetrieveFile(ctx, "hello.txt", io.Discard)
+		}()
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil && err != ErrTransferInProgress {
+			t.Errorf("errs[%d] = %v", i, err)
+		}
+	}
+	if _, err := c.RetrieveFile(ctx, "hello.txt", io.Discard); err != nil {
+		t.Error("client unusable after concurrent transfers:", err)
+	}
+}
+
+func TestValidType(t *testing.T) {
+	tests := []struct {
+		TypeCode string
+		Valid    bool
+	}{
+		{"A", true},
+		{"A N", true},
+		{"E T", true},
+		{"I", true},
+		{"L 8", true},
+		{"L 36", true},
+		{"", false},
+		{"X", false},
+		{"A X", false},
+		{"I N", false},
+		{"L", false},
+		{"L 0", false},
+		{"L x", false},
+	}
+	for i, tt := range tests {
+		if valid := validType(tt.TypeCode); valid != tt.Valid {
+			t.Errorf("tests[%d]: expected %t (got %t)", i, tt.Valid, valid)
+		}
+	}
+}
+
+func TestTransferType(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("data.bin", []byte("Hello\r\n"))
+	var types []string
+	s.handle("TYPE", func(sc *serverConn, arg string) {
+		types = append(types, arg)
+		sc.reply(200, "Okay")
+	})
+	c := s.dial(ctx)
+
+	_, conn, err := c.Transfer(ctx, "RETR data.bin", "l 8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(conn)
+	if cerr := conn.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "Hello\r\n" {
+		t.Errorf("data = %q (expected untranslated)", b)
+	}
+	if _, _, err := c.Transfer(ctx, "RETR data.bin", "L\r\nDELE data.bin"); err == nil {
+		t.Error("expected error for invalid type code")
+	}
+	if expected := []string{"L 8"}; !reflect.DeepEqual(types, expected) {
+		t.Errorf("TYPE %q (expected %q)", types, expected)
+	}
+}
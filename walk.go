@@ -0,0 +1,150 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"context"
+	"path"
+	"path/filepath"
+	"slices"
+)
+
+// WalkFunc is the type of the function called by Walk to visit each file or
+// directory. It follows the semantics of filepath.WalkFunc: if listing a
+// directory fails, the function is called with that directory and the error,
+// and returning filepath.SkipDir skips the directory.
+type WalkFunc func(path string, entry Entry, err error) error
+
+// Walk walks the file tree rooted at root using MLSD, calling fn for each
+// file or directory in the tree, including root. The cdir and pdir entries
+// are skipped. Each listing is read completely before descending into
+// its subdirectories, because a connection can't list multiple directories
+// simultaneously.
+//
+// Symbolic links are passed to fn as listed, unless FollowSymlinks is set.
+// Then links are resolved using MLST and passed to fn with the type and
+// facts of their target, and links to directories are descended into. To
+// avoid infinite loops, a link isn't followed if it resolves to a directory
+// that is being walked already, as identified by the unique fact, or if
+// maxSymlinks links have been followed to reach it.
+func (c *Client) Walk(ctx context.Context, root string, fn WalkFunc) error {
+	err := c.walk(ctx, root, Entry{Name: path.Base(root), Type: EntryDir}, fn, c.FollowSymlinks, nil, 0)
+	if err == filepath.SkipDir {
+		return nil
+	}
+	return err
+}
+
+// maxSymlinks is the maximum number of symbolic links followed by Walk
+// to reach an entry.
+const maxSymlinks = 8
+
+// walk walks the tree at name, following symbolic links if follow is set.
+// The unique facts of the directories being walked are in ancestors and
+// links is the number of links followed.
+func (c *Client) walk(ctx context.Context, name string, entry Entry, fn WalkFunc, follow bool, ancestors []string, links int) error {
+	if entry.Type != EntryDir {
+		return fn(name, entry, nil)
+	}
+
+	entries, err := c.MLSD(ctx, name)
+	for _, e := range entries {
+		if e.Type == EntryCurrentDir {
+			// Keep the name and link information of entry.
+			entry.Size, entry.ModTime, entry.Perm, entry.Facts = e.Size, e.ModTime, e.Perm, e.Facts
+		}
+	}
+	err1 := fn(name, entry, err)
+	if err != nil || err1 != nil {
+		return err1
+	}
+
+	if unique := entry.Facts["unique"]; unique != "" {
+		ancestors = append(ancestors, unique)
+	}
+	for _, e := range entries {
+		if e.Type == EntryCurrentDir || e.Type == EntryParentDir {
+			continue
+		}
+		p, followed := path.Join(name, e.Name), links
+		if e.Symlink && follow && links < maxSymlinks {
+			target, ok := c.resolveSymlink(ctx, p, e)
+			unique := target.Facts["unique"]
+			if ok && (unique == "" || !slices.Contains(ancestors, unique)) {
+				e = target
+				followed++
+			}
+		}
+		err := c.walk(ctx, p, e, fn, follow, ancestors, followed)
+		if err != nil && (e.Type != EntryDir || err != filepath.SkipDir) {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveSymlink returns the entry of the target of the link at name using
+// MLST. It reports false if the link can't be resolved to a file or
+// directory.
+func (c *Client) resolveSymlink(ctx context.Context, name string, link Entry) (Entry, bool) {
+	e, err := c.MLST(ctx, name)
+	if err != nil || (e.Type != EntryFile && e.Type != EntryDir) {
+		return Entry{}, false
+	}
+	e.Name, e.Symlink, e.Target = link.Name, true, link.Target
+	return e, true
+}
+
+// RemoveAll removes the file or directory at name and everything it
+// contains. The tree is listed as by Walk: files are deleted while walking
+// and directories are removed bottom-up afterwards. Symbolic links are
+// removed themselves, never followed, regardless of FollowSymlinks. If a
+// directory can't be listed or an entry can't be removed, RemoveAll
+// continues with the rest of the tree and returns the first error.
+//
+// Unlike os.RemoveAll, it returns an error if name doesn't exist, because
+// servers report a missing path like any other permanent failure.
+func (c *Client) RemoveAll(ctx context.Context, name string) error {
+	if err := c.Delete(ctx, name); err == nil {
+		return nil
+	} else if !IsPermanent(err) {
+		return err
+	}
+
+	var firstErr error
+	record := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	var dirs []string
+	root := Entry{Name: path.Base(name), Type: EntryDir}
+	err := c.walk(ctx, name, root, func(p string, e Entry, err error) error {
+		if err != nil {
+			record(err)
+		}
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case e.Symlink || e.Type != EntryDir:
+			if err := c.Delete(ctx, p); err != nil {
+				record(err)
+			}
+			if e.Type == EntryDir {
+				return filepath.SkipDir
+			}
+		default:
+			dirs = append(dirs, p)
+		}
+		return nil
+	}, false, nil, 0)
+	if err != nil {
+		return err
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := c.RemoveDir(ctx, dirs[i]); err != nil {
+			record(err)
+		}
+	}
+	return firstErr
+}
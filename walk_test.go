@@ -0,0 +1,242 @@
+// Copyright (c) 2026 Anner van Hardenbroek.
+
+package ftp
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	for _, name := range []string{"a.txt", "b/c.txt", "b/d/e.txt", "f/g.txt"} {
+		s.setFile(name, nil)
+	}
+	c := s.dial(ctx)
+
+	expected := []string{".", "b", "b/d", "b/d/e.txt", "b/c.txt", "f", "f/g.txt", "a.txt"}
+	var visited []string
+	err := c.Walk(ctx, ".", func(path string, entry Entry, err error) error {
+		if err != nil {
+			return err
+		}
+		visited = append(visited, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("visited = %q (expected %q)", visited, expected)
+	}
+}
+
+func TestWalkSkipDir(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	for _, name := range []string{"a.txt", "b/c.txt", "b/d/e.txt"} {
+		s.setFile(name, nil)
+	}
+	c := s.dial(ctx)
+
+	expected := []string{".", "b", "a.txt"}
+	var visited []string
+	err := c.Walk(ctx, ".", func(path string, entry Entry, err error) error {
+		if err != nil {
+			return err
+		}
+		visited = append(visited, path)
+		if entry.Type == EntryDir && path == "b" {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("visited = %q (expected %q)", visited, expected)
+	}
+}
+
+func TestWalkSymlinks(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	s.setFile("a/b.txt", nil)
+	s.handle("MLSD", func(sc *serverConn, arg string) {
+		lines := map[string][]string{
+			"":     {"type=cdir;unique=1; .", "type=dir;unique=2; a", "type=OS.unix=slink:a; link"},
+			"a":    {"type=cdir;unique=2; a", "type=file; b.txt", "type=OS.unix=slink:..; loop"},
+			"link": {"type=cdir;unique=2; link", "type=file; b.txt", "type=OS.unix=slink:..; loop"},
+		}[cleanPath(arg)]
+		sc.transfer(func(conn io.ReadWriter) error {
+			for _, line := range lines {
+				if _, err := io.WriteString(conn, line+"\r\n"); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+	s.handle("MLST", func(sc *serverConn, arg string) {
+		unique := map[string]string{"link": "2", "a/loop": "1", "link/loop": "1"}[arg]
+		sc.proto.PrintfLine("250-Listing %s", arg)
+		sc.proto.PrintfLine(" type=dir;unique=%s; %s", unique, arg)
+		sc.reply(250, "End")
+	})
+
+	tests := []struct {
+		Follow   bool
+		Expected []string
+		Symlinks []string
+	}{
+		{false, []string{".", "a", "a/b.txt", "a/loop", "link"}, []string{"a/loop", "link"}},
+		{true, []string{".", "a", "a/b.txt", "a/loop", "link", "link/b.txt", "link/loop"}, []string{"a/loop", "link", "link/loop"}},
+	}
+	for i, tt := range tests {
+		c := s.dial(ctx)
+		c.FollowSymlinks = tt.Follow
+		var visited, symlinks []string
+		err := c.Walk(ctx, ".", func(path string, entry Entry, err error) error {
+			if err != nil {
+				return err
+			}
+			visited = append(visited, path)
+			if entry.Symlink {
+				symlinks = append(symlinks, path)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(visited, tt.Expected) {
+			t.Errorf("tests[%d]: expected %q (got %q)", i, tt.Expected, visited)
+		}
+		if !reflect.DeepEqual(symlinks, tt.Symlinks) {
+			t.Errorf("tests[%d]: expected symlinks %q (got %q)", i, tt.Symlinks, symlinks)
+		}
+	}
+}
+
+func TestRemoveAll(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	for _, name := range []string{"a/b.txt", "a/c/d.txt", "a/c/e/f.txt", "other.txt"} {
+		s.setFile(name, nil)
+	}
+	s.handle("DELE", func(sc *serverConn, arg string) {
+		sc.s.mu.Lock()
+		_, ok := sc.s.files[arg]
+		if arg != "a/b.txt" {
+			delete(sc.s.files, arg)
+		}
+		sc.s.mu.Unlock()
+		switch {
+		case !ok:
+			sc.reply(550, "File not found")
+		case arg == "a/b.txt":
+			sc.reply(550, "Permission denied")
+		default:
+			sc.reply(250, "Deleted")
+		}
+	})
+	c := s.dial(ctx)
+
+	// Continue after failing to delete a/b.txt.
+	if err := c.RemoveAll(ctx, "a"); !IsFileUnavailable(err) {
+		t.Errorf("err = %v (expected 550 reply)", err)
+	}
+	var names []string
+	s.mu.Lock()
+	for name := range s.files {
+		names = append(names, name)
+	}
+	s.mu.Unlock()
+	slices.Sort(names)
+	if expected := []string{"a/b.txt", "other.txt"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("files = %q (expected %q)", names, expected)
+	}
+	if n := s.count("RMD"); n != 3 {
+		t.Errorf("RMD sent %d times (expected 3)", n)
+	}
+}
+
+func TestRemoveAllSymlinks(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	for _, name := range []string{"a/b.txt", "target/c.txt"} {
+		s.setFile(name, nil)
+	}
+	s.handle("MLSD", func(sc *serverConn, arg string) {
+		dir := cleanPath(arg)
+		if dir == "a/link" {
+			dir = "target"
+		}
+		lines, ok := sc.s.list(dir)
+		if !ok {
+			sc.reply(550, "Directory not found")
+			return
+		}
+		if dir == "a" {
+			lines = append(lines, "type=OS.unix=slink:/target; link")
+		}
+		sc.transfer(func(conn io.ReadWriter) error {
+			for _, line := range lines {
+				if _, err := io.WriteString(conn, line+"\r\n"); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+	s.handle("MLST", func(sc *serverConn, arg string) {
+		sc.proto.PrintfLine("250-Listing %s", arg)
+		sc.proto.PrintfLine(" type=dir; %s", arg)
+		sc.reply(250, "End")
+	})
+	var deleted []string
+	s.handle("DELE", func(sc *serverConn, arg string) {
+		name := strings.Replace(arg, "a/link/", "target/", 1)
+		sc.s.mu.Lock()
+		deleted = append(deleted, arg)
+		_, ok := sc.s.files[name]
+		delete(sc.s.files, name)
+		sc.s.mu.Unlock()
+		if !ok && arg != "a/link" {
+			sc.reply(550, "File not found")
+			return
+		}
+		sc.reply(250, "Deleted")
+	})
+	c := s.dial(ctx)
+	c.FollowSymlinks = true
+
+	if err := c.RemoveAll(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if expected := []string{"a", "a/b.txt", "a/link"}; !reflect.DeepEqual(deleted, expected) {
+		t.Errorf("deleted = %q (expected %q)", deleted, expected)
+	}
+	if _, ok := s.files["target/c.txt"]; !ok {
+		t.Error("target/c.txt removed through the link")
+	}
+}
+
+func TestRemoveAllNotFound(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	c := s.dial(ctx)
+
+	if err := c.RemoveAll(ctx, "missing"); !IsFileUnavailable(err) {
+		t.Errorf("err = %v (expected 550 reply)", err)
+	}
+}